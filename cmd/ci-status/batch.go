@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ci-status/internal/daemon"
+	"ci-status/internal/forge"
+)
+
+var (
+	ErrBatchWithContext = errors.New("--batch reads context names from its input; do not pass one as an argument")
+	ErrContextMissing   = errors.New("context name missing (or use --batch)")
+)
+
+// batchRateLimitRetries is how often one status is retried after the forge
+// rate-limited it; batchBackoff is the wait when the forge did not say how
+// long (doubled per retry) and batchMaxWait caps any single wait.
+var (
+	batchRateLimitRetries = 3
+	batchBackoff          = 2 * time.Second
+	batchMaxWait          = time.Minute
+)
+
+// batchLine is one JSON line of 'set --batch' input. Empty fields fall back
+// to the --state, --description, --url and --commit flags.
+type batchLine struct {
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Commit      string `json:"commit"`
+}
+
+// batchEntry is a parsed input line, or the reason it cannot be posted.
+type batchEntry struct {
+	line int
+	opts forge.StatusOpts
+	err  error
+}
+
+// parseBatch reads JSON lines from r. Blank lines are skipped; malformed
+// lines become entries with err set so the summary reports them by number.
+func parseBatch(r io.Reader, cfg SetConfig) ([]batchEntry, error) {
+	var entries []batchEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		entry := batchEntry{line: n}
+		l := batchLine{State: cfg.State, Description: cfg.Description, URL: cfg.URL, Commit: cfg.Commit}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&l); err != nil {
+			entry.err = fmt.Errorf("invalid JSON: %w", err)
+		} else if l.Context == "" {
+			entry.err = errors.New("context missing")
+		}
+		entry.opts = forge.StatusOpts{Commit: l.Commit, Context: l.Context, Description: l.Description, TargetURL: l.URL}
+		if entry.err == nil {
+			entry.opts.State, entry.err = parseState(l.State)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch: %w", err)
+	}
+	return entries, nil
+}
+
+// executeBatch posts one status per line of the --batch input.
+//
+// Flow:
+//  1. Parses and validates every line (also outside CI, like set).
+//  2. Detects the forge client and commit once, as set does (with --socket,
+//     the daemon's are used instead).
+//  3. Posts valid lines with at most --concurrency requests in flight,
+//     waiting and retrying when the forge rate-limits a request.
+//  4. Prints one summary line per input line (a setResult each with
+//     --output json) and fails if any line was not posted.
+func executeBatch(ctx context.Context, cfg SetConfig) error {
+	var r io.Reader = os.Stdin
+	if cfg.Batch != "-" {
+		f, err := os.Open(cfg.Batch)
+		if err != nil {
+			return quiet(fmt.Errorf("read batch: %w", err), cfg.Silent)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		r = f
+	}
+	entries, err := parseBatch(r, cfg)
+	if err != nil {
+		return quiet(err, cfg.Silent)
+	}
+
+	var forgeName string
+	switch {
+	case cfg.Socket != "":
+		postBatchToDaemon(ctx, cfg, entries)
+	case cfg.DryRun || isCI(cfg.Silent):
+		forgeName = postBatch(ctx, cfg, entries)
+	default:
+		for i := range entries {
+			if entries[i].err == nil {
+				entries[i].err = errSkippedNotCI
+			}
+		}
+	}
+
+	failed := 0
+	for _, e := range entries {
+		if e.err != nil && !errors.Is(e.err, errSkippedNotCI) {
+			failed++
+		}
+		reportBatchEntry(cfg, forgeName, e)
+	}
+	if failed > 0 {
+		return quiet(fmt.Errorf("%d of %d statuses were not posted", failed, len(entries)), cfg.Silent)
+	}
+	return nil
+}
+
+// errSkippedNotCI marks lines that were valid but not posted outside CI.
+var errSkippedNotCI = errors.New("CI environment variable not set")
+
+// postBatch detects the forge once and posts every valid entry, recording
+// failures in the entries. It returns the forge name for the summary.
+func postBatch(ctx context.Context, cfg SetConfig, entries []batchEntry) string {
+	client, clientErr := setClient(cfg)
+	commit, commitErr := forge.DetectCommit("")
+
+	limit := max(cfg.Concurrency, 1)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := range entries {
+		e := &entries[i]
+		if e.err != nil {
+			continue
+		}
+		if clientErr != nil {
+			e.err = clientErr
+			continue
+		}
+		if e.opts.Commit == "" {
+			if commitErr != nil {
+				e.err = fmt.Errorf("commit not available: %w", commitErr)
+				continue
+			}
+			if commit == "" {
+				e.err = errors.New("commit not available")
+				continue
+			}
+			e.opts.Commit = commit
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			e.err = postRateLimited(ctx, client, e.opts)
+		}()
+	}
+	wg.Wait()
+	return forge.Name(client)
+}
+
+// postBatchToDaemon queues every valid entry in the --socket daemon over
+// at most --concurrency connections. The daemon coalesces the updates and
+// retries failed posts itself, waiting out rate limits, so entries only fail
+// here when the daemon cannot be reached or rejects them.
+func postBatchToDaemon(ctx context.Context, cfg SetConfig, entries []batchEntry) {
+	var valid []*batchEntry
+	for i := range entries {
+		if entries[i].err == nil {
+			valid = append(valid, &entries[i])
+		}
+	}
+	queue := make(chan *batchEntry)
+	var wg sync.WaitGroup
+	for range min(max(cfg.Concurrency, 1), len(valid)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := daemon.Dial(ctx, cfg.Socket)
+			if client != nil {
+				defer func() {
+					_ = client.Close()
+				}()
+			}
+			for e := range queue {
+				if err != nil {
+					e.err = err
+					continue
+				}
+				e.err = client.SetStatus(ctx, e.opts)
+			}
+		}()
+	}
+	for _, e := range valid {
+		queue <- e
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// postRateLimited posts opts, waiting out *forge.RateLimitError rejections
+// up to batchRateLimitRetries times.
+func postRateLimited(ctx context.Context, client forge.ForgeClient, opts forge.StatusOpts) error {
+	backoff := batchBackoff
+	for attempt := 0; ; attempt++ {
+		err := client.SetStatus(ctx, opts)
+		var rl *forge.RateLimitError
+		if err == nil || !errors.As(err, &rl) || attempt == batchRateLimitRetries {
+			return err
+		}
+		wait := rl.RetryAfter
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		timer := time.NewTimer(min(wait, batchMaxWait))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// reportBatchEntry prints the outcome of one line: a setResult on stdout
+// with --output json, otherwise a summary line on stderr.
+func reportBatchEntry(cfg SetConfig, forgeName string, e batchEntry) {
+	if cfg.Output == outputJSON {
+		res := setResult{
+			Line:        e.line,
+			Context:     e.opts.Context,
+			Commit:      e.opts.Commit,
+			Forge:       forgeName,
+			State:       string(e.opts.State),
+			Description: e.opts.Description,
+			TargetURL:   e.opts.TargetURL,
+			Posted:      e.err == nil && cfg.Socket == "",
+			Queued:      e.err == nil && cfg.Socket != "",
+			DryRun:      cfg.DryRun,
+		}
+		switch {
+		case errors.Is(e.err, errSkippedNotCI):
+			res.Skipped = e.err.Error()
+		case e.err != nil:
+			res.Error = e.err.Error()
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(res)
+		return
+	}
+	if cfg.Silent {
+		return
+	}
+	switch {
+	case e.err == nil && cfg.Socket != "":
+		fmt.Fprintf(os.Stderr, "line %d: %s %s: queued\n", e.line, e.opts.Context, e.opts.State)
+	case e.err == nil:
+		fmt.Fprintf(os.Stderr, "line %d: %s %s: posted\n", e.line, e.opts.Context, e.opts.State)
+	case errors.Is(e.err, errSkippedNotCI):
+		fmt.Fprintf(os.Stderr, "line %d: %s %s: skipped\n", e.line, e.opts.Context, e.opts.State)
+	default:
+		fmt.Fprintf(os.Stderr, "line %d: %s: %v\n", e.line, e.opts.Context, e.err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ci-status/internal/credentials/credentialstest"
+	"ci-status/internal/daemon"
+	"ci-status/internal/forge"
+)
+
+func TestParseBatch(t *testing.T) {
+	input := `{"context":"shard-1","state":"success","description":"412 passed","url":"https://ci/1"}
+
+{"context":"shard-2"}
+{"context":"shard-3","state":"bogus"}
+{"state":"success"}
+{"context":"shard-4","extra":true}
+not json
+`
+	entries, err := parseBatch(strings.NewReader(input), SetConfig{State: "failure", Description: "default", Commit: "abc"})
+	if err != nil {
+		t.Fatalf("parseBatch: %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("got %d entries, want 6 (blank lines skipped)", len(entries))
+	}
+
+	first := entries[0]
+	want := forge.StatusOpts{Commit: "abc", Context: "shard-1", State: forge.StateSuccess, Description: "412 passed", TargetURL: "https://ci/1"}
+	if first.err != nil || first.line != 1 || first.opts != want {
+		t.Fatalf("entry 1 = %+v", first)
+	}
+	if second := entries[1]; second.err != nil || second.line != 3 || second.opts.State != forge.StateFailure || second.opts.Description != "default" {
+		t.Fatalf("entry 2 should use the flag defaults: %+v", second)
+	}
+	for _, e := range entries[2:] {
+		if e.err == nil {
+			t.Errorf("line %d should be invalid: %+v", e.line, e)
+		}
+	}
+}
+
+// flakyClient rate-limits the first failures posts.
+type flakyClient struct {
+	recordingClient
+	failures int
+	calls    int
+}
+
+func (c *flakyClient) SetStatus(ctx context.Context, opts forge.StatusOpts) error {
+	c.calls++
+	if c.calls <= c.failures {
+		return &forge.RateLimitError{Err: errors.New("429 Too Many Requests")}
+	}
+	return c.recordingClient.SetStatus(ctx, opts)
+}
+
+func TestPostRateLimitedRetries(t *testing.T) {
+	old := batchBackoff
+	batchBackoff = time.Millisecond
+	t.Cleanup(func() { batchBackoff = old })
+
+	client := &flakyClient{failures: 2}
+	if err := postRateLimited(t.Context(), client, forge.StatusOpts{Context: "lint"}); err != nil {
+		t.Fatalf("postRateLimited: %v", err)
+	}
+	if client.calls != 3 || len(client.snapshot()) != 1 {
+		t.Fatalf("calls = %d, posts = %d; want 3 calls and 1 post", client.calls, len(client.snapshot()))
+	}
+
+	client = &flakyClient{failures: batchRateLimitRetries + 1}
+	err := postRateLimited(t.Context(), client, forge.StatusOpts{Context: "lint"})
+	var rl *forge.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want RateLimitError after %d retries", err, batchRateLimitRetries)
+	}
+}
+
+func TestExecuteSet_BatchDryRunFailsOnInvalidLines(t *testing.T) {
+	credentialstest.Isolate(t)
+	path := t.TempDir() + "/batch.jsonl"
+	input := "{\"context\":\"a\",\"state\":\"success\",\"commit\":\"abc\"}\n{\"context\":\"b\",\"state\":\"bogus\"}\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := executeSet(t.Context(), SetConfig{Batch: path, DryRun: true, Silent: true, Concurrency: 2})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 statuses were not posted") {
+		t.Fatalf("err = %v, want one failed line", err)
+	}
+}
+
+func TestPostBatchToDaemon(t *testing.T) {
+	dir, err := os.MkdirTemp("", "cis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "d.sock")
+	l, err := daemon.Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &recordingClient{}
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- (&daemon.Server{Client: client, Commit: "abc", Interval: time.Hour}).Serve(ctx, l) }()
+
+	entries := []batchEntry{{line: 1, err: errors.New("invalid")}}
+	for i := range 5 {
+		entries = append(entries, batchEntry{line: i + 2, opts: forge.StatusOpts{Context: fmt.Sprintf("shard-%d", i), State: forge.StateSuccess}})
+	}
+	postBatchToDaemon(t.Context(), SetConfig{Socket: path, Concurrency: 2}, entries)
+	for _, e := range entries[1:] {
+		if e.err != nil {
+			t.Errorf("line %d: %v", e.line, e.err)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if got := client.snapshot(); len(got) != 5 {
+		t.Fatalf("posts = %+v, want the 5 valid lines", got)
+	}
+}
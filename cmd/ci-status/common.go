@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
+	"ci-status/internal/channel"
+	"ci-status/internal/config"
+	"ci-status/internal/executor"
 	"ci-status/internal/forge"
 )
 
@@ -46,28 +55,198 @@ func isCI(silent bool) bool {
 
 // initForge centralizes the logic for detecting and initializing the forge
 // client and the commit SHA. It returns a nil client if not in a CI
-// environment or if detection fails.
-func initForge(forgeOverride, commitOverride string, silent bool) (forge.ForgeClient, string) {
+// environment or if detection fails. The client drops repeats of the last
+// posted status per context and sink (forge.DedupClient).
+//
+// Several forges (repeated --forge) are posted to together through a
+// forge.MultiClient; sinks that cannot be detected are warnings as long as
+// one remains.
+func initForge(forges []string, target forge.Sink, policy config.ForgePolicy, commitOverride string, silent bool) (forge.ForgeClient, string) {
 	if !isCI(silent) {
 		return nil, ""
 	}
 
-	client, err := forge.DetectClient(forgeOverride)
+	client, err := detectForge(forges, target, policy, silent)
+	if err != nil && !silent {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if client == nil {
+		return nil, ""
+	}
+
+	commit, err := forge.DetectCommit(commitOverride)
 	if err != nil {
 		if !silent {
 			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-		return nil, ""
+		// Allow returning a client even if commit detection fails,
+		// but the commit string will be empty.
+	}
+
+	return dedup(client), commit
+}
+
+// detectForge wraps forge.DetectClients, sending partial post failures
+// under --forge-policy any to stderr as warnings. target carries the
+// --remote, --repo and --api-url overrides. A non-nil client may come with
+// an error naming the sinks that were not detected.
+func detectForge(forges []string, target forge.Sink, policy config.ForgePolicy, silent bool) (forge.ForgeClient, error) {
+	client, err := forge.DetectClients(forges, target, policy.RequireAll())
+	if m, ok := client.(*forge.MultiClient); ok {
+		m.OnPartialError = func(err error) {
+			if !silent {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+	return client, err
+}
+
+// dedup wraps client (each sink of a forge.MultiClient) in a
+// forge.DedupClient. Sinks are deduplicated separately: under --forge-policy
+// any a status one sink rejected must still be retried there.
+func dedup(client forge.ForgeClient) forge.ForgeClient {
+	if m, ok := client.(*forge.MultiClient); ok {
+		for i, c := range m.Clients {
+			m.Clients[i] = forge.NewDedupClient(c)
+		}
+		return m
+	}
+	return forge.NewDedupClient(client)
+}
+
+// dryRun wraps client (each sink of a forge.MultiClient) so requests are
+// printed to out instead of sent.
+func dryRun(client forge.ForgeClient, out io.Writer) forge.ForgeClient {
+	if m, ok := client.(*forge.MultiClient); ok {
+		for i, c := range m.Clients {
+			m.Clients[i] = forge.NewDryRunClient(c, out)
+		}
+		return m
+	}
+	return forge.NewDryRunClient(client, out)
+}
+
+// initDryRunForge is initForge for --dry-run: detection also runs outside
+// CI, and the client prints its requests to out instead of sending them.
+// Detection failures are warnings; statuses are then printed without a
+// request so scripts can still be tested without a token.
+func initDryRunForge(forges []string, target forge.Sink, policy config.ForgePolicy, commitOverride string, silent bool, out io.Writer) (forge.ForgeClient, string) {
+	client, err := detectForge(forges, target, policy, silent)
+	if err != nil && !silent {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 
 	commit, err := forge.DetectCommit(commitOverride)
+	if err != nil && !silent {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	return dedup(dryRun(client, out)), commit
+}
+
+// channelInterval is how often status channels are polled (shortened in tests).
+var channelInterval = channel.DefaultInterval
+
+// startStatusChannel exports the CI_STATUS_* variables for one context,
+// including the sink flags statuses are reported with (see sinkEnviron), and
+// opens its status channel. Lines the command appends to $CI_STATUS_CHANNEL
+// are forwarded as StateRunning updates on top of running; onUpdate, when
+// not nil, is called before each of them is posted.
+//
+// The returned stop func must be called before the final status is posted:
+// it waits for an in-flight update so a late "running" post cannot
+// overwrite the result. Channel problems are warnings; the command still
+// runs, just without CI_STATUS_CHANNEL.
+func startStatusChannel(ctx context.Context, client forge.ForgeClient, commit string, silent bool, running forge.StatusOpts, sink config.SinkFlags, onUpdate func()) (env []string, stop func()) {
+	status := executor.StatusEnv{
+		Context:   running.Context,
+		Commit:    commit,
+		Forge:     forge.Name(client),
+		TargetURL: running.TargetURL,
+		Sink:      sinkEnviron(sink),
+	}
+	ch, err := channel.Create()
 	if err != nil {
 		if !silent {
 			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-		// Allow returning a client even if commit detection fails,
-		// but the commit string will be empty.
+		return status.Environ(), func() {}
 	}
+	status.Channel = ch.Path
+	stop = ch.Watch(ctx, channelInterval, func(u channel.Update) {
+		update := running
+		if u.Description != "" {
+			update.Description = u.Description
+		}
+		if u.TargetURL != "" {
+			update.TargetURL = u.TargetURL
+		}
+		if onUpdate != nil {
+			onUpdate()
+		}
+		postStatus(ctx, client, commit, silent, update, "progress")
+	})
+	return status.Environ(), stop
+}
 
-	return client, commit
+// sinkEnviron renders the sink flags that are set as the CI_STATUS_*
+// variables bound to them, so a nested ci-status call (e.g. set in a
+// --repo job) reports to the same repository with the same credentials.
+// Files are made absolute since the command may run in --cwd.
+func sinkEnviron(s config.SinkFlags) []string {
+	abs := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		if p, err := filepath.Abs(path); err == nil {
+			return p
+		}
+		return path
+	}
+	installation := ""
+	if s.GitHubAppInstallationID != 0 {
+		installation = strconv.FormatInt(s.GitHubAppInstallationID, 10)
+	}
+	var env []string
+	for _, kv := range [][2]string{
+		{"remote", s.Remote},
+		{"repo", s.Repo},
+		{"api-url", s.APIURL},
+		{"token-file", abs(s.TokenFile)},
+		{"github-app-id", s.GitHubAppID},
+		{"github-app-key-file", abs(s.GitHubAppKeyFile)},
+		{"github-app-installation-id", installation},
+	} {
+		if kv[1] != "" {
+			env = append(env, envName(kv[0])+"="+kv[1])
+		}
+	}
+	return env
+}
+
+// delayedPost calls post after delay (immediately when delay <= 0). The
+// returned stop func cancels a post that has not started and waits for one
+// in flight, so a late pending status cannot overwrite the next one. stop
+// may be called more than once.
+func delayedPost(delay time.Duration, post func()) (stop func()) {
+	if delay <= 0 {
+		post()
+		return func() {}
+	}
+	var mu sync.Mutex
+	stopped := false
+	timer := time.AfterFunc(delay, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			post()
+		}
+	})
+	return func() {
+		timer.Stop()
+		mu.Lock()
+		stopped = true
+		mu.Unlock()
+	}
 }
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ci-status/internal/config"
+	"ci-status/internal/forge"
+)
+
+// recordingClient collects every posted status.
+type recordingClient struct {
+	mu    sync.Mutex
+	posts []forge.StatusOpts
+}
+
+func (c *recordingClient) SetStatus(_ context.Context, opts forge.StatusOpts) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.posts = append(c.posts, opts)
+	return nil
+}
+
+func (c *recordingClient) snapshot() []forge.StatusOpts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]forge.StatusOpts(nil), c.posts...)
+}
+
+func TestStartStatusChannelForwardsUpdates(t *testing.T) {
+	old := channelInterval
+	channelInterval = 10 * time.Millisecond
+	t.Cleanup(func() { channelInterval = old })
+
+	client := &recordingClient{}
+	running := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateRunning, Description: "Running...", TargetURL: "https://ci/1"}
+	var updates atomic.Int32
+	env, stop := startStatusChannel(t.Context(), client, "abc", true, running, config.SinkFlags{}, func() {
+		// Called before the post, so it must not see it yet.
+		if len(client.snapshot()) == 0 {
+			updates.Add(1)
+		}
+	})
+
+	for _, want := range []string{"CI_STATUS_CONTEXT=lint", "CI_STATUS_COMMIT=abc", "CI_STATUS_URL=https://ci/1"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("env %v missing %s", env, want)
+		}
+	}
+	var path string
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "CI_STATUS_CHANNEL="); ok {
+			path = v
+		}
+	}
+	if path == "" {
+		t.Fatalf("env %v has no CI_STATUS_CHANNEL", env)
+	}
+
+	if err := os.WriteFile(path, []byte("description: 40/100 tests\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(client.snapshot()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	posts := client.snapshot()
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1: %+v", len(posts), posts)
+	}
+	if updates.Load() != 1 {
+		t.Errorf("onUpdate called %d times before posting, want 1", updates.Load())
+	}
+	want := running
+	want.Description = "40/100 tests"
+	if posts[0] != want {
+		t.Fatalf("posted %+v, want %+v", posts[0], want)
+	}
+}
+
+func TestDelayedPost(t *testing.T) {
+	var mu sync.Mutex
+	posts := 0
+	post := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		posts++
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return posts
+	}
+
+	delayedPost(0, post)
+	if count() != 1 {
+		t.Fatal("zero delay should post immediately")
+	}
+
+	stop := delayedPost(time.Hour, post)
+	stop()
+	stop()
+	if count() != 1 {
+		t.Fatal("stopped before the delay: nothing should be posted")
+	}
+
+	stop = delayedPost(time.Millisecond, post)
+	deadline := time.Now().Add(2 * time.Second)
+	for count() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	if count() != 2 {
+		t.Fatal("post should go out once the delay elapsed")
+	}
+}
+
+// failingClient fails the first fails posts, then records them.
+type failingClient struct {
+	recordingClient
+	fails int
+}
+
+func (c *failingClient) SetStatus(ctx context.Context, opts forge.StatusOpts) error {
+	c.mu.Lock()
+	if c.fails > 0 {
+		c.fails--
+		c.mu.Unlock()
+		return errors.New("502 Bad Gateway")
+	}
+	c.mu.Unlock()
+	return c.recordingClient.SetStatus(ctx, opts)
+}
+
+func TestDedupPerSink(t *testing.T) {
+	flaky, ok := &failingClient{fails: 1}, &recordingClient{}
+	client := dedup(&forge.MultiClient{Clients: []forge.ForgeClient{flaky, ok}, OnPartialError: func(error) {}})
+	opts := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateSuccess}
+
+	for range 2 {
+		if err := client.SetStatus(t.Context(), opts); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+	}
+	// The sink that failed gets the repeat; the other one drops it.
+	if got := flaky.snapshot(); len(got) != 1 {
+		t.Errorf("failed sink posts = %+v, want the status sent again", got)
+	}
+	if got := ok.snapshot(); len(got) != 1 {
+		t.Errorf("healthy sink posts = %+v, want the repeat dropped", got)
+	}
+}
+
+func TestStatusEnvTargetsNestedCalls(t *testing.T) {
+	client := &recordingClient{}
+	running := forge.StatusOpts{Commit: "abc", Context: "deploy", State: forge.StateRunning, TargetURL: "https://ci/1"}
+	sink := config.SinkFlags{Repo: "other/repo", APIURL: "https://git.example.com/api/v1", TokenFile: "token"}
+	env, stop := startStatusChannel(t.Context(), client, "abc", true, running, sink, nil)
+	stop()
+
+	// A nested "ci-status set" sees the exported variables as its flag
+	// defaults and must report to the same repository.
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		t.Setenv(name, value)
+	}
+	var nested config.SinkFlags
+	var url, commit string
+	cmd := &cobra.Command{Use: "set"}
+	nested.Register(cmd.Flags())
+	cmd.Flags().StringVar(&url, "url", "", "")
+	cmd.Flags().StringVar(&commit, "commit", "", "")
+	if _, err := applyEnv(cmd); err != nil {
+		t.Fatalf("applyEnv: %v", err)
+	}
+
+	token, err := filepath.Abs("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.SinkFlags{Repo: "other/repo", APIURL: "https://git.example.com/api/v1", TokenFile: token}
+	if nested.Repo != want.Repo || nested.APIURL != want.APIURL || nested.TokenFile != want.TokenFile {
+		t.Errorf("nested sink = %+v, want %+v", nested, want)
+	}
+	if url != "https://ci/1" || commit != "abc" {
+		t.Errorf("nested url, commit = %q, %q; want https://ci/1, abc", url, commit)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configShowCommand string
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect ci-status configuration files",
+	Long: `Inspect ci-status configuration files.
+
+Settings are read from $XDG_CONFIG_HOME/ci-status/config.{toml,yaml,yml} and
+from .ci-status.{toml,yaml,yml} in the repository root (the project file wins).
+Keys are flag names. [defaults] applies to every context; [contexts."name"]
+entries override it for a context name or glob ("test-*"), the exact name
+winning over globs:
+
+  [defaults]
+  forge = "github"
+  timeout = "10m"
+
+  [contexts."test-*"]
+  success-desc = "Tests passed"
+
+Precedence: command-line flags > CI_STATUS_* environment variables > config
+files > built-in defaults.`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [context-name]",
+	Short: "Print the effective settings and where each one comes from",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		contextName := ""
+		if len(args) == 1 {
+			contextName = args[0]
+		}
+		return showConfig(configShowCommand, contextName)
+	},
+}
+
+func init() {
+	configShowCmd.Flags().StringVar(&configShowCommand, "command", "run", "Show the settings of this command (run, set, multi, pipeline)")
+	ConfigCmd.AddCommand(configShowCmd)
+	Command.AddCommand(ConfigCmd)
+}
+
+// showConfig applies the environment and config files to the named command's flags and
+// prints every flag with its effective value and source.
+func showConfig(commandName, contextName string) error {
+	target, _, err := Command.Find([]string{commandName})
+	if err != nil || target == Command || target == ConfigCmd {
+		return fmt.Errorf("unknown command %q", commandName)
+	}
+	sources, err := applySettings(target, contextName)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	target.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name != "help" {
+			names = append(names, f.Name)
+		}
+	})
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, name := range names {
+		f := target.Flags().Lookup(name)
+		source, ok := sources[name]
+		if !ok {
+			source = sourceDefault
+		}
+		fmt.Fprintf(w, "%s\t%q\t%s\n", name, f.Value.String(), source)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"ci-status/internal/config"
+	"ci-status/internal/forge"
+	"github.com/spf13/cobra"
+)
+
+// DoctorConfig holds the configuration for the 'doctor' command.
+type DoctorConfig struct {
+	// SinkFlags and Commit are the same overrides run and set accept; every
+	// --forge value is diagnosed separately.
+	config.SinkFlags
+	Commit string
+	// CheckAPI makes a read-only API call to validate the token.
+	CheckAPI bool
+}
+
+var doctorConfig DoctorConfig
+
+// ErrDoctorFailed is returned when detection would leave run/set without a
+// client or commit; the report itself says which step failed.
+var ErrDoctorFailed = errors.New("status reporting would not work (see above)")
+
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Explain how the forge, repository, token and commit are detected",
+	Long: `Explain how the forge, repository, token and commit are detected.
+
+Prints every detection step run and set perform: the CI provider, the git
+remote used, each forge loader and why it matched or was rejected, the API
+base URL, the token source (masked) and where the commit SHA comes from.
+With --check-api, the repository is also fetched (read-only) to validate
+the token.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := applySettings(cmd, ""); err != nil {
+			return err
+		}
+		return executeDoctor(cmd.Context(), os.Stdout, doctorConfig)
+	},
+}
+
+func init() {
+	doctorConfig.SinkFlags.Register(DoctorCmd.Flags())
+	DoctorCmd.Flags().Lookup("forge").Usage = "Forge to diagnose: github, gitea, or NAME=REMOTE with a git remote name or URL (repeatable; default: auto-detect)"
+	DoctorCmd.Flags().StringVar(&doctorConfig.Commit, "commit", "", "Override commit SHA")
+	DoctorCmd.Flags().BoolVar(&doctorConfig.CheckAPI, "check-api", false, "Validate the token with a read-only API call")
+
+	documentEnv(DoctorCmd)
+	Command.AddCommand(DoctorCmd)
+}
+
+// ciProviders maps the variables CI services set to their names, checked in order.
+var ciProviders = []struct{ env, name string }{
+	{"GITHUB_ACTIONS", "GitHub Actions"},
+	{"GITEA_ACTIONS", "Gitea Actions"},
+	{"FORGEJO_ACTIONS", "Forgejo Actions"},
+	{"GITLAB_CI", "GitLab CI"},
+	{"BITBUCKET_BUILD_NUMBER", "Bitbucket Pipelines"},
+	{"BUILDKITE", "Buildkite"},
+	{"CIRCLECI", "CircleCI"},
+	{"JENKINS_URL", "Jenkins"},
+	{"WOODPECKER", "Woodpecker"},
+	{"DRONE", "Drone"},
+}
+
+// ciProvider names the CI service from its environment, or "" if unknown.
+func ciProvider() string {
+	for _, p := range ciProviders {
+		if os.Getenv(p.env) != "" {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// executeDoctor prints the detection report to w.
+//
+// Flow:
+//  1. CI environment (statuses are only posted when CI is set).
+//  2. forge.Diagnose for every --forge value: remote, loaders, client,
+//     token and commit.
+//  3. Optionally a read-only API call with the selected client.
+//
+// Returns ErrDoctorFailed when run/set would post nothing.
+func executeDoctor(ctx context.Context, w io.Writer, cfg DoctorConfig) error {
+	ok := true
+
+	fmt.Fprintln(w, "CI environment:")
+	provider := ciProvider()
+	if provider == "" {
+		provider = "unknown"
+	}
+	fmt.Fprintf(w, "  provider: %s\n", provider)
+	if os.Getenv("CI") == "" {
+		fmt.Fprintln(w, "  CI: not set (run and set skip status reporting)")
+		ok = false
+	} else {
+		fmt.Fprintf(w, "  CI: %q\n", os.Getenv("CI"))
+	}
+
+	specs := cfg.Forge
+	if len(specs) == 0 {
+		specs = []string{""}
+	}
+	target := cfg.Sink()
+	diags := make([]forge.Diagnosis, len(specs))
+	for i, spec := range specs {
+		diags[i] = forge.Diagnose(target.For(spec), cfg.Commit)
+		if !printSink(w, spec, diags[i]) {
+			ok = false
+		}
+	}
+
+	d := diags[0]
+	fmt.Fprintln(w, "Commit:")
+	if d.CommitErr != nil {
+		fmt.Fprintf(w, "  error: %s: %v\n", d.CommitSource, d.CommitErr)
+		ok = false
+	} else {
+		fmt.Fprintf(w, "  %s (from %s)\n", d.Commit, d.CommitSource)
+	}
+
+	if cfg.CheckAPI {
+		fmt.Fprintln(w, "API check:")
+		for _, d := range diags {
+			if !checkAPI(ctx, w, d.Client) {
+				ok = false
+			}
+		}
+	}
+
+	if !ok {
+		return ErrDoctorFailed
+	}
+	return nil
+}
+
+// printSink prints the remote and loader sections of one --forge value and
+// reports whether a client was detected.
+func printSink(w io.Writer, spec string, d forge.Diagnosis) bool {
+	fmt.Fprintln(w, "Remote:")
+	if d.RemoteErr != nil {
+		fmt.Fprintf(w, "  error: %v\n", d.RemoteErr)
+		// --repo does not need the remote; Diagnose then still ran the loaders.
+		if d.Loaders == nil {
+			return false
+		}
+	} else {
+		fmt.Fprintf(w, "  %s: %s\n", d.Remote, d.RemoteURL)
+	}
+
+	fmt.Fprintln(w, "Forge loaders:")
+	if spec != "" {
+		fmt.Fprintf(w, "  override: --forge %s\n", spec)
+	}
+	for _, l := range d.Loaders {
+		mark := " "
+		if l.Selected {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "  %s %-8s %s\n", mark, l.Name, l.Reason)
+	}
+	if d.ClientErr != nil {
+		fmt.Fprintf(w, "  error: %v\n", d.ClientErr)
+		return false
+	}
+	fmt.Fprintf(w, "  forge: %s\n", forge.Name(d.Client))
+	fmt.Fprintf(w, "  repository: %s/%s\n", d.Owner, d.Repo)
+	fmt.Fprintf(w, "  API base URL: %s\n", d.APIBaseURL)
+	fmt.Fprintf(w, "  token: %s from %s\n", d.MaskedToken, d.TokenSource)
+	return true
+}
+
+// checkAPI runs the read-only access check and reports whether the token
+// can read the repository and, when the API says so, push statuses.
+func checkAPI(ctx context.Context, w io.Writer, client forge.ForgeClient) bool {
+	gh, isGitHub := client.(*forge.GitHubClient)
+	if !isGitHub {
+		fmt.Fprintln(w, "  skipped: no forge client")
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	check, err := gh.CheckAccess(ctx)
+	fmt.Fprintf(w, "  GET %s\n", check.URL)
+	if err != nil {
+		fmt.Fprintf(w, "  error: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(w, "  status: %s\n", check.Status)
+	if check.Scopes != "" {
+		fmt.Fprintf(w, "  token scopes: %s\n", check.Scopes)
+	}
+	if check.Push != nil {
+		fmt.Fprintf(w, "  push permission: %t\n", *check.Push)
+		if !*check.Push {
+			fmt.Fprintln(w, "  warning: commit statuses need push (or statuses: write) access")
+		}
+	}
+	return check.Status != "" && check.Status[0] == '2'
+}
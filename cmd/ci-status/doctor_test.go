@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"ci-status/internal/config"
+	"ci-status/internal/credentials/credentialstest"
+)
+
+func TestExecuteDoctor_ExplainsDetection(t *testing.T) {
+	t.Setenv("CI", "true")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_TOKEN", "ghp_0123456789abcdef")
+	t.Setenv("GITHUB_SHA", "abc123")
+
+	var out bytes.Buffer
+	err := executeDoctor(t.Context(), &out, DoctorConfig{SinkFlags: config.SinkFlags{Forge: []string{"github"}}})
+	if err != nil {
+		t.Fatalf("executeDoctor: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{
+		"provider: GitHub Actions",
+		"origin: ",
+		"* github",
+		"skipped: --forge github",
+		"API base URL: https://api.github.com",
+		"token: ghp_…(20 chars) from GITHUB_TOKEN",
+		"abc123 (from GITHUB_SHA)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "0123456789abcdef") {
+		t.Errorf("output leaks the token:\n%s", out.String())
+	}
+}
+
+func TestExecuteDoctor_NotCIFails(t *testing.T) {
+	t.Setenv("CI", "")
+	credentialstest.Isolate(t)
+
+	var out bytes.Buffer
+	err := executeDoctor(t.Context(), &out, DoctorConfig{Commit: "abc123"})
+	if !errors.Is(err, ErrDoctorFailed) {
+		t.Fatalf("err = %v, want ErrDoctorFailed", err)
+	}
+	if !strings.Contains(out.String(), "CI: not set") || !strings.Contains(out.String(), "no forge token found") {
+		t.Errorf("output should explain the failures:\n%s", out.String())
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"ci-status/internal/config"
+	"ci-status/internal/executor"
+	"ci-status/internal/forge"
+	"github.com/spf13/cobra"
+)
+
+// MultiConfig holds the configuration for the 'multi' command, which runs
+// several commands concurrently and reports one status context per command.
+type MultiConfig struct {
+	// Jobs are "context=command" pairs given with --job.
+	Jobs []string
+	// JobsFile is a file with one "context=command" pair per line ("-" reads stdin).
+	JobsFile string
+	// Concurrency caps how many jobs run at the same time.
+	Concurrency int
+	// Timeout is the per-job time limit.
+	Timeout time.Duration
+	jobOptions
+}
+
+// jobOptions are the settings multi and pipeline share: where statuses go
+// and what each job's statuses say. runJob reads only these.
+type jobOptions struct {
+	// SinkFlags choose the forge and credentials statuses are reported
+	// with; ForgePolicy applies to several --forge values.
+	config.SinkFlags
+	ForgePolicy config.ForgePolicy
+	// Commit overrides the detected commit SHA.
+	Commit string
+	// PR overrides the detected PR number.
+	PR string
+	// URL provides a link to further details, shared by every job.
+	URL string
+	// QueuedDesc is the description posted for every job before any starts.
+	QueuedDesc string
+	// PendingDesc is the description shown while a job is running.
+	PendingDesc string
+	// SuccessDesc is the description shown when a job exits with code 0.
+	SuccessDesc string
+	// FailureDesc is the description shown when a job exits non-zero.
+	FailureDesc string
+	// Silent suppresses warnings and the final summary.
+	Silent bool
+}
+
+// multiJob is one parsed "context=command" pair. Env and Timeout are only
+// set by callers that have per-job settings (e.g. pipeline steps, or
+// multi's --timeout).
+type multiJob struct {
+	Context string
+	Script  string
+	Env     []string
+	// Timeout is the job's time limit; zero means none.
+	Timeout time.Duration
+}
+
+var multiConfig MultiConfig
+
+var MultiCmd = &cobra.Command{
+	Use:     "multi --job 'context=command' [--job ...]",
+	Aliases: []string{"run-many"},
+	Short:   "Run several commands concurrently, reporting one status each",
+	Long: `Run several commands concurrently, reporting one status context each.
+
+Jobs are "context=command" pairs, given with --job or one per line in
+--jobs-file (blank lines and lines starting with # are ignored). Commands run
+through the platform shell, so pipelines and && work as in CI YAML. Output is
+prefixed with the context name. Exits non-zero if any job did not succeed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Contexts are per job/step, so only [defaults] applies.
+		if _, err := applySettings(cmd, ""); err != nil {
+			return err
+		}
+		// cmd.Context() so a parent ExecuteContext cancel reaches jobs and posts.
+		return executeMulti(cmd.Context(), multiConfig)
+	},
+}
+
+func init() {
+	MultiCmd.Flags().StringArrayVar(&multiConfig.Jobs, "job", nil, "Job as 'context=command' (repeatable)")
+	MultiCmd.Flags().StringVar(&multiConfig.JobsFile, "jobs-file", "", "File with one 'context=command' job per line ('-' for stdin)")
+	MultiCmd.Flags().IntVarP(&multiConfig.Concurrency, "concurrency", "j", runtime.NumCPU(), "Maximum number of jobs running at once")
+	multiConfig.SinkFlags.Register(MultiCmd.Flags())
+	MultiCmd.Flags().Var(&multiConfig.ForgePolicy, "forge-policy", "With several --forge values, whether all or any of them must accept a status")
+	MultiCmd.Flags().StringVar(&multiConfig.Commit, "commit", "", "Override commit SHA")
+	MultiCmd.Flags().StringVar(&multiConfig.PR, "pr", "", "Override pull request number")
+	MultiCmd.Flags().StringVar(&multiConfig.URL, "url", "", "Target URL for details")
+	MultiCmd.Flags().StringVar(&multiConfig.QueuedDesc, "queued-desc", "Queued", "Description shown while a job waits for a free slot")
+	MultiCmd.Flags().StringVar(&multiConfig.PendingDesc, "pending-desc", "Running...", "Description shown while a job is running")
+	MultiCmd.Flags().StringVar(&multiConfig.SuccessDesc, "success-desc", "Passed", "Description shown when a job exits with code 0")
+	MultiCmd.Flags().StringVar(&multiConfig.FailureDesc, "failure-desc", "Failed", "Description shown when a job exits with non-zero code")
+	MultiCmd.Flags().DurationVar(&multiConfig.Timeout, "timeout", 0, "Maximum time allowed for each job")
+	MultiCmd.Flags().BoolVar(&multiConfig.Silent, "silent", false, "Suppress warnings and the summary")
+
+	documentEnv(MultiCmd)
+	Command.AddCommand(MultiCmd)
+}
+
+// parseJobs turns "context=command" lines into jobs. The first "=" splits,
+// so commands may contain "=" but context names may not. Duplicate contexts
+// are rejected: they would overwrite each other's status on the forge.
+func parseJobs(lines []string) ([]multiJob, error) {
+	var jobs []multiJob
+	seen := map[string]bool{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, script, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		script = strings.TrimSpace(script)
+		if !ok || name == "" || script == "" {
+			return nil, fmt.Errorf("invalid job %q (want context=command)", line)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate job context %q", name)
+		}
+		seen[name] = true
+		jobs = append(jobs, multiJob{Context: name, Script: script})
+	}
+	return jobs, nil
+}
+
+// loadJobs collects --job values followed by --jobs-file lines.
+func loadJobs(cfg MultiConfig) ([]multiJob, error) {
+	lines := append([]string(nil), cfg.Jobs...)
+	if cfg.JobsFile != "" {
+		var r io.Reader
+		if cfg.JobsFile == "-" {
+			r = os.Stdin
+		} else {
+			f, err := os.Open(cfg.JobsFile)
+			if err != nil {
+				return nil, fmt.Errorf("read jobs file: %w", err)
+			}
+			defer func() {
+				_ = f.Close()
+			}()
+			r = f
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read jobs file: %w", err)
+		}
+	}
+
+	jobs, err := parseJobs(lines)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, errors.New("no jobs given (use --job or --jobs-file)")
+	}
+	return jobs, nil
+}
+
+// executeMulti runs every job with bounded concurrency and reports each one
+// as its own status context.
+//
+// Flow:
+//  1. Parses jobs and initializes the shared forge client (via initForge).
+//  2. Posts a 'pending' (queued) status for every job so the forge shows the whole set.
+//  3. Runs jobs through the platform shell, at most Concurrency at a time.
+//     Each job posts its own running and final statuses.
+//  4. Prints a summary and returns an error if any job did not succeed.
+//
+// Unlike run, multi does not exit with a wrapped exit code: there are many.
+// ctx should come from the cobra command (cmd.Context()).
+func executeMulti(ctx context.Context, cfg MultiConfig) error {
+	jobs, err := loadJobs(cfg)
+	if err != nil {
+		return quiet(err, cfg.Silent)
+	}
+	for i := range jobs {
+		jobs[i].Timeout = cfg.Timeout
+	}
+
+	target := cfg.Sink()
+	client, commit := initForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Commit, cfg.Silent)
+
+	for _, job := range jobs {
+		postStatus(ctx, client, commit, cfg.Silent, forge.StatusOpts{
+			Commit:      commit,
+			Context:     job.Context,
+			State:       forge.StatePending,
+			Description: cfg.QueuedDesc,
+			TargetURL:   cfg.URL,
+		}, "queued")
+	}
+
+	limit := cfg.Concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	// Jobs share the terminal; LockedWriter keeps prefixed lines whole.
+	stdout := &executor.LockedWriter{W: os.Stdout}
+	stderr := &executor.LockedWriter{W: os.Stderr}
+
+	states := make([]forge.State, len(jobs))
+	exitCodes := make([]int, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			states[i], exitCodes[i] = runJob(ctx, client, commit, cfg.jobOptions, job, stdout, stderr)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, job := range jobs {
+		if !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "%s: %s (exit code %d)\n", job.Context, states[i], exitCodes[i])
+		}
+		if states[i] != forge.StateSuccess {
+			failed = append(failed, job.Context)
+		}
+	}
+	if len(failed) > 0 {
+		return quiet(fmt.Errorf("%d of %d jobs did not succeed: %s", len(failed), len(jobs), strings.Join(failed, ", ")), cfg.Silent)
+	}
+	return nil
+}
+
+// runJob runs one job and posts its running and final statuses.
+// It returns the posted final state and the job's exit code.
+func runJob(ctx context.Context, client forge.ForgeClient, commit string, cfg jobOptions, job multiJob, stdout, stderr io.Writer) (forge.State, int) {
+	base := forge.StatusOpts{
+		Commit:    commit,
+		Context:   job.Context,
+		TargetURL: cfg.URL,
+	}
+
+	running := base
+	running.State = forge.StateRunning
+	running.Description = cfg.PendingDesc
+	postStatus(ctx, client, commit, cfg.Silent, running, "pending")
+
+	e := executor.New()
+	// Concurrent jobs cannot share the terminal's stdin.
+	e.Stdin = nil
+	e.Stdout = stdout
+	e.Stderr = stderr
+	e.Prefix = job.Context
+	e.Shell = true
+	statusEnv, stopChannel := startStatusChannel(ctx, client, commit, cfg.Silent, running, cfg.SinkFlags, nil)
+	// User-supplied variables come last so they can override CI_STATUS_*,
+	// as in run.
+	e.Env = append(statusEnv, job.Env...)
+	exitCode, err := e.Run(ctx, job.Timeout, job.Script, nil)
+	stopChannel()
+
+	final := base
+	if errors.Is(err, executor.ErrTimeout) {
+		final.State = forge.StateError
+		final.Description = "Timed out"
+	} else {
+		final.State, final.Description = finalStatus(exitCode, err, config.Config{
+			SuccessDesc:  cfg.SuccessDesc,
+			FailureDesc:  cfg.FailureDesc,
+			SuccessCodes: config.ExitCodes{{Min: 0, Max: 0}},
+		})
+		if err != nil && exitCode == 0 {
+			// Start failure: surface it and report a non-zero code in the summary.
+			if !cfg.Silent {
+				fmt.Fprintf(stderr, "[%s] %v\n", job.Context, err)
+			}
+			exitCode = 1
+		}
+	}
+	postStatus(ctx, client, commit, cfg.Silent, final, "final")
+	return final.State, exitCode
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ci-status/internal/forge"
+)
+
+func TestParseJobs(t *testing.T) {
+	jobs, err := parseJobs([]string{
+		"# linters",
+		"",
+		"lint = golangci-lint run",
+		"env=FOO=bar make check",
+	})
+	if err != nil {
+		t.Fatalf("parseJobs: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs))
+	}
+	if jobs[0].Context != "lint" || jobs[0].Script != "golangci-lint run" {
+		t.Fatalf("job 0 = %+v", jobs[0])
+	}
+	if jobs[1].Context != "env" || jobs[1].Script != "FOO=bar make check" {
+		t.Fatalf("job 1 = %+v, command should keep later '='", jobs[1])
+	}
+
+	for _, bad := range [][]string{{"no-separator"}, {"=cmd"}, {"ctx="}, {"a=x", "a=y"}} {
+		if _, err := parseJobs(bad); err == nil {
+			t.Errorf("parseJobs(%q) expected error", bad)
+		}
+	}
+}
+
+func TestLoadJobsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs")
+	if err := os.WriteFile(path, []byte("vet=go vet ./...\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := loadJobs(MultiConfig{Jobs: []string{"lint=true"}, JobsFile: path})
+	if err != nil {
+		t.Fatalf("loadJobs: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Context != "lint" || jobs[1].Context != "vet" {
+		t.Fatalf("jobs = %+v, want flag job then file job", jobs)
+	}
+
+	if _, err := loadJobs(MultiConfig{}); err == nil {
+		t.Fatal("expected error without jobs")
+	}
+}
+
+func TestExecuteMulti_NotCI(t *testing.T) {
+	t.Setenv("CI", "")
+
+	err := executeMulti(t.Context(), MultiConfig{
+		Jobs:        []string{"ok=true", "also-ok=exit 0"},
+		Concurrency: 2,
+		jobOptions:  jobOptions{Silent: true},
+	})
+	if err != nil {
+		t.Fatalf("all jobs passed, got %v", err)
+	}
+
+	err = executeMulti(t.Context(), MultiConfig{
+		Jobs:        []string{"ok=true", "broken=exit 2"},
+		Concurrency: 1,
+		jobOptions:  jobOptions{Silent: true},
+	})
+	if err == nil {
+		t.Fatal("expected error when a job fails")
+	}
+	if !isQuietError(err) {
+		t.Fatalf("silent failure should be quiet, got %T", err)
+	}
+	if !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("error should name the failed job, got %v", err)
+	}
+}
+
+// TestRunJobEnvOverridesStatusEnv ensures a job's own variables win over the
+// CI_STATUS_* ones, as in run.
+func TestRunJobEnvOverridesStatusEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "context")
+	state, exitCode := runJob(t.Context(), nil, "abc123", jobOptions{Silent: true}, multiJob{
+		Context: "lint",
+		Script:  `printf %s "$CI_STATUS_CONTEXT" >"$OUT"`,
+		Env:     []string{"CI_STATUS_CONTEXT=mine", "OUT=" + out},
+	}, io.Discard, io.Discard)
+	if state != forge.StateSuccess || exitCode != 0 {
+		t.Fatalf("runJob = %s, %d", state, exitCode)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "mine" {
+		t.Fatalf("CI_STATUS_CONTEXT = %q, %v; want the job's value", data, err)
+	}
+}
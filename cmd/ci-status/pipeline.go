@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"ci-status/internal/executor"
+	"ci-status/internal/forge"
+	"ci-status/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+// PipelineConfig holds the configuration for the 'pipeline' command, which
+// runs the steps of a .ci-status.yml file as a dependency graph.
+type PipelineConfig struct {
+	// File is the pipeline definition. Empty means the first of
+	// pipeline.DefaultFiles found in the working directory.
+	File string
+	// Concurrency caps how many steps run at the same time.
+	Concurrency int
+	// SkippedDesc is the description posted for steps whose dependency failed.
+	SkippedDesc string
+	// jobOptions are shared with multi; each step runs as a multi job.
+	jobOptions
+}
+
+var pipelineConfig PipelineConfig
+
+var PipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "Run the steps of .ci-status.yml as a dependency graph",
+	Long: `Run the steps of .ci-status.yml as a dependency graph.
+
+Each step has a name, a command (run through the platform shell) and optional
+context, env, timeout and depends_on keys:
+
+  steps:
+    - name: build
+      command: go build ./...
+    - name: test
+      context: Tests
+      command: go test ./...
+      timeout: 10m
+      depends_on: [build]
+
+Every step is posted as pending when the pipeline starts, running when it
+starts, and gets its final status when it ends. Steps whose dependencies did
+not succeed are posted as errors and never run. Exits non-zero if any step
+did not succeed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Contexts are per job/step, so only [defaults] applies.
+		if _, err := applySettings(cmd, ""); err != nil {
+			return err
+		}
+		// cmd.Context() so a parent ExecuteContext cancel reaches steps and posts.
+		return executePipeline(cmd.Context(), pipelineConfig)
+	},
+}
+
+func init() {
+	PipelineCmd.Flags().StringVarP(&pipelineConfig.File, "file", "f", "", "Pipeline file (default .ci-status.yml)")
+	PipelineCmd.Flags().IntVarP(&pipelineConfig.Concurrency, "concurrency", "j", runtime.NumCPU(), "Maximum number of steps running at once")
+	pipelineConfig.SinkFlags.Register(PipelineCmd.Flags())
+	PipelineCmd.Flags().Var(&pipelineConfig.ForgePolicy, "forge-policy", "With several --forge values, whether all or any of them must accept a status")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.Commit, "commit", "", "Override commit SHA")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.PR, "pr", "", "Override pull request number")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.URL, "url", "", "Target URL for details")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.QueuedDesc, "queued-desc", "Queued", "Description shown while a step waits to start")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.PendingDesc, "pending-desc", "Running...", "Description shown while a step is running")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.SuccessDesc, "success-desc", "Passed", "Description shown when a step exits with code 0")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.FailureDesc, "failure-desc", "Failed", "Description shown when a step exits with non-zero code")
+	PipelineCmd.Flags().StringVar(&pipelineConfig.SkippedDesc, "skipped-desc", "Skipped: dependency failed", "Description shown for steps skipped because a dependency failed")
+	PipelineCmd.Flags().BoolVar(&pipelineConfig.Silent, "silent", false, "Suppress warnings and the summary")
+
+	documentEnv(PipelineCmd)
+	Command.AddCommand(PipelineCmd)
+}
+
+// executePipeline runs a pipeline file and reports each step as its own
+// status context.
+//
+// Flow:
+//  1. Loads and validates the pipeline (unknown deps and cycles fail early,
+//     before any status is posted).
+//  2. Initializes the shared forge client (via initForge) and posts every
+//     step as 'pending' (queued).
+//  3. Executes the DAG; each step reuses multi's runJob for its running and
+//     final statuses. Dependents of failed steps are posted as StateError.
+//  4. Prints a summary and returns an error if any step did not succeed.
+//
+// ctx should come from the cobra command (cmd.Context()).
+func executePipeline(ctx context.Context, cfg PipelineConfig) error {
+	path := cfg.File
+	if path == "" {
+		found, err := pipeline.Find()
+		if err != nil {
+			return quiet(err, cfg.Silent)
+		}
+		path = found
+	}
+	p, err := pipeline.Load(path)
+	if err != nil {
+		return quiet(err, cfg.Silent)
+	}
+
+	target := cfg.Sink()
+	client, commit := initForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Commit, cfg.Silent)
+
+	for _, step := range p.Steps {
+		postStatus(ctx, client, commit, cfg.Silent, forge.StatusOpts{
+			Commit:      commit,
+			Context:     step.Context,
+			State:       forge.StatePending,
+			Description: cfg.QueuedDesc,
+			TargetURL:   cfg.URL,
+		}, "queued")
+	}
+
+	stdout := &executor.LockedWriter{W: os.Stdout}
+	stderr := &executor.LockedWriter{W: os.Stderr}
+
+	outcomes := p.Execute(ctx, cfg.Concurrency,
+		func(ctx context.Context, step pipeline.Step) bool {
+			state, _ := runJob(ctx, client, commit, cfg.jobOptions, multiJob{
+				Context: step.Context,
+				Script:  step.Command,
+				Env:     step.Environ(),
+				Timeout: step.Timeout,
+			}, stdout, stderr)
+			return state == forge.StateSuccess
+		},
+		func(step pipeline.Step, failedDep string) {
+			desc := cfg.SkippedDesc
+			if failedDep == "" {
+				desc = "Cancelled"
+			}
+			postStatus(ctx, client, commit, cfg.Silent, forge.StatusOpts{
+				Commit:      commit,
+				Context:     step.Context,
+				State:       forge.StateError,
+				Description: desc,
+				TargetURL:   cfg.URL,
+			}, "skipped")
+		},
+	)
+
+	var failed []string
+	for _, step := range p.Steps {
+		outcome := outcomes[step.Name]
+		if !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", step.Name, outcome)
+		}
+		if outcome != pipeline.Succeeded {
+			failed = append(failed, step.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return quiet(fmt.Errorf("%d of %d steps did not succeed: %s", len(failed), len(p.Steps), strings.Join(failed, ", ")), cfg.Silent)
+	}
+	return nil
+}
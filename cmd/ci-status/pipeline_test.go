@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecutePipeline_NotCI(t *testing.T) {
+	t.Setenv("CI", "")
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	path := filepath.Join(dir, "pipeline.yml")
+	yaml := `
+steps:
+  - name: build
+    command: exit 1
+  - name: test
+    command: touch "$MARKER"
+    env:
+      MARKER: ` + marker + `
+    depends_on: [build]
+  - name: lint
+    command: test -n "$MARKER"
+    env:
+      MARKER: set
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := executePipeline(t.Context(), PipelineConfig{File: path, Concurrency: 2, jobOptions: jobOptions{Silent: true}})
+	if err == nil {
+		t.Fatal("expected error when a step fails")
+	}
+	if !isQuietError(err) {
+		t.Fatalf("silent failure should be quiet, got %T", err)
+	}
+	if !strings.Contains(err.Error(), "build") || !strings.Contains(err.Error(), "test") {
+		t.Fatalf("error should name failed and skipped steps, got %v", err)
+	}
+	if strings.Contains(err.Error(), "lint") {
+		t.Fatalf("independent step with env should pass, got %v", err)
+	}
+	if _, statErr := os.Stat(marker); statErr == nil {
+		t.Fatal("dependent step ran even though its dependency failed")
+	}
+}
+
+func TestExecutePipeline_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.yml")
+	if err := os.WriteFile(path, []byte("steps:\n  - {name: a, command: x, depends_on: [nope]}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := executePipeline(t.Context(), PipelineConfig{File: path})
+	if err == nil || !strings.Contains(err.Error(), "unknown step") {
+		t.Fatalf("want validation error, got %v", err)
+	}
+}
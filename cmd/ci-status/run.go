@@ -5,27 +5,59 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"ci-status/internal/cache"
+	"ci-status/internal/changes"
 	"ci-status/internal/config"
 	"ci-status/internal/executor"
 	"ci-status/internal/forge"
+	"ci-status/internal/report"
 	"github.com/spf13/cobra"
 )
 
 var runConfig config.Config
 
-var ErrCommandMissing = errors.New("command missing after --")
+var ErrCommandMissing = errors.New("command missing after -- (or use --shell)")
+
+var ErrShellAndCommand = errors.New("--shell and a command after -- are mutually exclusive")
+
+// contextPlaceholder in --prefix is replaced by the context name; a bare
+// --prefix uses it as the whole label.
+const contextPlaceholder = "{context}"
+
+// outputLabel expands --prefix for the executor. --timestamps alone labels
+// lines like a bare --prefix, so the time reads "[lint 00:01:23]" rather
+// than an anonymous "[00:01:23]".
+func outputLabel(prefix string, timestamps bool, contextName string) string {
+	if prefix == "" && timestamps {
+		prefix = contextPlaceholder
+	}
+	return strings.ReplaceAll(prefix, contextPlaceholder, contextName)
+}
 
 var RunCmd = &cobra.Command{
-	Use:   "run [context-name] -- [command] [args...]",
+	Use:   "run [context-name] {-- [command] [args...] | --shell 'script'}",
 	Short: "Run a command and report status to a forge",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		runConfig.ContextName = args[0]
+		if _, err := applySettings(cmd, runConfig.ContextName); err != nil {
+			return err
+		}
 
 		dashIdx := cmd.ArgsLenAtDash()
+		hasCommand := dashIdx != -1 && dashIdx < len(args)
 
-		if dashIdx == -1 || dashIdx >= len(args) {
+		if runConfig.Shell != "" {
+			if hasCommand {
+				return ErrShellAndCommand
+			}
+			return execute(cmd.Context(), runConfig)
+		}
+		if !hasCommand {
 			return ErrCommandMissing
 		}
 
@@ -39,50 +71,112 @@ var RunCmd = &cobra.Command{
 }
 
 func init() {
-	RunCmd.Flags().StringVar(&runConfig.Forge, "forge", "", "Override automatic forge detection")
+	runConfig.SinkFlags.Register(RunCmd.Flags())
+	RunCmd.Flags().Var(&runConfig.ForgePolicy, "forge-policy", "With several --forge values, whether all or any of them must accept a status")
 	RunCmd.Flags().StringVar(&runConfig.Commit, "commit", "", "Override commit SHA")
 	RunCmd.Flags().StringVar(&runConfig.PR, "pr", "", "Override pull request number")
 	RunCmd.Flags().StringVar(&runConfig.URL, "url", "", "Target URL for details")
+	RunCmd.Flags().StringVar(&runConfig.Shell, "shell", "", "Run this script through the platform shell ($SHELL/sh -c, cmd /C on Windows) instead of a command after --")
+	RunCmd.Flags().StringVar(&runConfig.Cwd, "cwd", "", "Working directory for the command")
+	RunCmd.Flags().StringArrayVar(&runConfig.Env, "env", nil, "Extra environment variable for the command as KEY=VALUE (repeatable)")
+	RunCmd.Flags().StringArrayVar(&runConfig.EnvFiles, "env-file", nil, "Load KEY=VALUE lines from a dotenv-style file (repeatable)")
 	RunCmd.Flags().StringVar(&runConfig.PendingDesc, "pending-desc", "Running...", "Description shown while command is running")
+	RunCmd.Flags().DurationVar(&runConfig.MinPendingDelay, "min-pending-delay", 0, "Only post the running status if the command is still running after this long (e.g. 2s)")
 	RunCmd.Flags().StringVar(&runConfig.SuccessDesc, "success-desc", "Passed", "Description shown when command exits with code 0")
 	RunCmd.Flags().StringVar(&runConfig.FailureDesc, "failure-desc", "Failed", "Description shown when command exits with non-zero code")
+	RunCmd.Flags().StringVar(&runConfig.ErrorDesc, "error-desc", "Errored", "Description shown when the exit code is in --error-codes")
+	RunCmd.Flags().StringVar(&runConfig.SkipDesc, "skip-desc", "Skipped", "Description shown when the exit code is in --skip-codes")
+	runConfig.SuccessCodes = config.ExitCodes{{Min: 0, Max: 0}}
+	RunCmd.Flags().Var(&runConfig.SuccessCodes, "success-codes", "Exit codes reported as success (comma-separated, ranges like 2-5 allowed)")
+	RunCmd.Flags().Var(&runConfig.ErrorCodes, "error-codes", "Exit codes reported as error instead of failure")
+	RunCmd.Flags().Var(&runConfig.SkipCodes, "skip-codes", "Exit codes reported as skipped (posted as success with --skip-desc)")
+	RunCmd.Flags().BoolVar(&runConfig.NormalizeExitCode, "normalize-exit-code", false, "Exit 0 for success/skipped and 1 for failure/error instead of the command's exit code")
 	RunCmd.Flags().DurationVar(&runConfig.Timeout, "timeout", 0, "Maximum time allowed for command execution")
+	RunCmd.Flags().IntVar(&runConfig.Retries, "retries", 0, "Rerun the command up to this many times when it fails")
+	RunCmd.Flags().Var(&runConfig.RetryOnCodes, "retry-on-codes", "Only retry on these exit codes (default: any failure code; include 124 to retry timeouts)")
+	RunCmd.Flags().DurationVar(&runConfig.RetryDelay, "retry-delay", 0, "Pause between retries")
+	RunCmd.Flags().StringSliceVar(&runConfig.Paths, "paths", nil, "Only run when files matching these globs changed since --base (e.g. 'web/**')")
+	RunCmd.Flags().StringVar(&runConfig.Base, "base", "", "Base ref for --paths (default: auto-detect from pull request environment)")
+	RunCmd.Flags().StringVar(&runConfig.UnchangedDesc, "unchanged-desc", "Skipped: no relevant changes", "Description shown when --paths skipped the command")
+	RunCmd.Flags().StringSliceVar(&runConfig.CacheKeyFiles, "cache-key-files", nil, "Globs of input files; skip the command when the same inputs already passed (e.g. 'go.sum,**/*.go')")
+	RunCmd.Flags().StringVar(&runConfig.CacheDir, "cache-dir", "", "Directory for cached results (default: user cache dir)")
+	RunCmd.Flags().DurationVar(&runConfig.CacheTTL, "cache-ttl", 0, "Ignore cached results older than this (default: never expire)")
+	RunCmd.Flags().BoolVar(&runConfig.PTY, "pty", false, "Run the command under a pseudo-terminal (Linux only; merges stdout and stderr)")
+	RunCmd.Flags().Uint16Var(&runConfig.PTYCols, "pty-cols", executor.DefaultPTYCols, "Pseudo-terminal width for --pty")
+	RunCmd.Flags().Uint16Var(&runConfig.PTYRows, "pty-rows", executor.DefaultPTYRows, "Pseudo-terminal height for --pty")
+	RunCmd.Flags().StringVar(&runConfig.Prefix, "prefix", "", "Label each output line with --prefix=LABEL ('{context}' expands to the context name; bare --prefix uses it)")
+	RunCmd.Flags().Lookup("prefix").NoOptDefVal = contextPlaceholder
+	RunCmd.Flags().BoolVar(&runConfig.Timestamps, "timestamps", false, "Add the elapsed time to each output line, e.g. '[lint 00:01:23] ...'")
+	RunCmd.Flags().StringVar(&runConfig.ReportJSON, "report-json", "", "Write a JSON report (context, commit, forge, final state and description, exit code, timings, retries, resource usage, failed status posts) to this file")
+	RunCmd.Flags().BoolVar(&runConfig.PrintUsage, "print-usage", false, "Print duration and resource usage (CPU, max RSS, block I/O) to stderr")
+	RunCmd.Flags().Var(&runConfig.MemoryLimit, "memory-limit", "Memory limit for the command tree, e.g. 4G (Linux cgroup v2 with delegation)")
+	RunCmd.Flags().Float64Var(&runConfig.CPULimit, "cpu-limit", 0, "CPU limit for the command tree in CPUs, e.g. 2 or 0.5 (Linux cgroup v2 with delegation)")
+	RunCmd.Flags().BoolVar(&runConfig.DryRun, "dry-run", false, "Print the status requests (token masked) to stderr instead of sending them; works outside CI")
 	RunCmd.Flags().BoolVar(&runConfig.Silent, "silent", false, "Suppress output when running in noop mode or on errors")
 
+	documentEnv(RunCmd)
 	Command.AddCommand(RunCmd)
 }
 
 // postStatus reports a forge status when a client and commit are available.
 // API failures are warnings only (unless silent); they must not fail the run.
-// label is the human phrase in the warning ("pending", "timeout", "final").
-func postStatus(ctx context.Context, client forge.ForgeClient, commit string, silent bool, opts forge.StatusOpts, label string) {
+// label is the human phrase in the warning ("pending", "retry", "timeout", "final").
+// The error is returned for callers that record it (run's --report-json).
+func postStatus(ctx context.Context, client forge.ForgeClient, commit string, silent bool, opts forge.StatusOpts, label string) error {
 	if client == nil || commit == "" {
-		return
+		return nil
 	}
-	if err := client.SetStatus(ctx, opts); err != nil && !silent {
+	err := client.SetStatus(ctx, opts)
+	if err != nil && !silent {
 		fmt.Fprintf(os.Stderr, "Warning: failed to set %s status: %v\n", label, err)
 	}
+	return err
 }
 
 // execute orchestrates the core logic of the 'run' command.
 //
 // Flow:
-// 1. Validates the CI environment and initializes the forge client (via initForge).
-// 2. Reports a 'pending' status to the forge (e.g., GitHub check run).
-// 3. Executes the user-specified command with a timeout context.
-// 4. Catches specific errors like timeouts (reporting 'error' status and exiting with 124).
-// 5. Reports the final status ('success' or 'failure') based on the command's exit code.
-// 6. Exits the process with the command's exit code.
+//  1. Validates the CI environment and initializes the forge client (via initForge,
+//     or initDryRunForge with --dry-run).
+//  2. With --paths or --cache-key-files, skips the command (posting success) when
+//     nothing relevant changed or the same inputs already passed.
+//  3. Reports a 'pending' status to the forge (e.g., GitHub check run), after
+//     --min-pending-delay if set and only if the command is still running.
+//  4. Executes the user-specified command with a timeout context (retrying per --retries),
+//     exporting CI_STATUS_* variables and forwarding $CI_STATUS_CHANNEL updates.
+//  5. Catches specific errors like timeouts (reporting 'error' status and exiting with 124)
+//     and OOM kills under --memory-limit.
+//  6. Reports the final status mapped from the exit code (see finalStatus).
+//  7. Exits the process with the command's exit code (or 0/1 with --normalize-exit-code).
 //
 // Side Effects:
 // - Makes HTTP requests to the forge API.
 // - Prints warnings/errors to stderr.
+// - Writes the --report-json file when the command ran.
 // - Terminates the process using os.Exit (does not return).
 //
 // ctx should come from the cobra command (cmd.Context()) so a parent
 // ExecuteContext cancel reaches status posts and the wrapped command.
 func execute(ctx context.Context, cfg config.Config) error {
-	client, commit := initForge(cfg.Forge, cfg.Commit, cfg.Silent)
+	// --shell replaces the command after --; from here on the script is the
+	// command (cache keys and reports included).
+	if cfg.Shell != "" {
+		cfg.Command, cfg.Args = cfg.Shell, nil
+	}
+	// Bad --env/--env-file input is a usage error: fail before any status post.
+	env, err := commandEnv(cfg)
+	if err != nil {
+		return quiet(err, cfg.Silent)
+	}
+
+	target := cfg.Sink()
+	var client forge.ForgeClient
+	var commit string
+	if cfg.DryRun {
+		client, commit = initDryRunForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Commit, cfg.Silent, os.Stderr)
+	} else {
+		client, commit = initForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Commit, cfg.Silent)
+	}
 
 	// Shared StatusOpts fields for every post in this run.
 	base := forge.StatusOpts{
@@ -90,23 +184,163 @@ func execute(ctx context.Context, cfg config.Config) error {
 		Context:   cfg.ContextName,
 		TargetURL: cfg.URL,
 	}
+	// Failed posts of the command's statuses, for --report-json. The delayed
+	// pending post runs on its own goroutine, hence the lock.
+	var postErrors []report.PostError
+	var postErrorsMu sync.Mutex
+	post := func(opts forge.StatusOpts, label string) {
+		if err := postStatus(ctx, client, commit, cfg.Silent, opts, label); err != nil {
+			postErrorsMu.Lock()
+			postErrors = append(postErrors, report.PostError{Status: label, Error: err.Error()})
+			postErrorsMu.Unlock()
+		}
+	}
+
+	// skip posts a passing final status for a command that is not run
+	// (--paths, result cache) and writes its report.
+	skip := func(desc string) {
+		skipped := base
+		skipped.State = forge.StateSuccess
+		skipped.Description = desc
+		post(skipped, "final")
+		rep := report.New(cfg.ContextName, commit, append([]string{cfg.Command}, cfg.Args...), executor.Result{})
+		rep.Forge = forge.Name(client)
+		rep.State, rep.Description = string(skipped.State), desc
+		writeReport(rep, postErrors, cfg, false)
+	}
+
+	// Path filter: skip only when we positively know nothing relevant changed.
+	// Missing base refs or git errors fall back to running the command.
+	if len(cfg.Paths) > 0 {
+		baseRef := changes.DetectBase(cfg.Base)
+		if baseRef == "" {
+			if !cfg.Silent {
+				fmt.Fprintln(os.Stderr, "Warning: --paths given but no base ref detected (use --base), running command")
+			}
+		} else if files, err := changes.Files(ctx, baseRef); err != nil {
+			if !cfg.Silent {
+				fmt.Fprintf(os.Stderr, "Warning: %v, running command\n", err)
+			}
+		} else if !changes.AnyMatch(cfg.Paths, files) {
+			if !cfg.Silent {
+				fmt.Fprintf(os.Stderr, "No changes matching --paths since %s, skipping command\n", baseRef)
+			}
+			skip(cfg.UnchangedDesc)
+			os.Exit(0)
+		}
+	}
+
+	// Result cache: identical inputs that already passed are reported without
+	// running. Cache problems only disable caching; they never fail the run.
+	var resultCache cache.Cache
+	var cacheKey string
+	if len(cfg.CacheKeyFiles) > 0 {
+		resultCache = cache.Cache{Dir: cfg.CacheDir, TTL: cfg.CacheTTL}
+		if resultCache.Dir == "" {
+			dir, err := cache.DefaultDir()
+			if err != nil && !cfg.Silent {
+				fmt.Fprintf(os.Stderr, "Warning: %v, result cache disabled\n", err)
+			}
+			resultCache.Dir = dir
+		}
+		// --cache-key-files are relative to --cwd, where the command runs.
+		root := cfg.Cwd
+		if root == "" {
+			root = "."
+		}
+		key, err := cache.Key(root, cfg.CacheKeyFiles, cfg.ContextName, cfg.Command, cfg.Args, env)
+		switch {
+		case resultCache.Dir == "":
+		case err != nil:
+			if !cfg.Silent {
+				fmt.Fprintf(os.Stderr, "Warning: %v, result cache disabled\n", err)
+			}
+		default:
+			if entry, ok := resultCache.Lookup(key); ok {
+				desc := "Cached result"
+				if entry.Commit != "" {
+					sha := entry.Commit
+					if len(sha) > 7 {
+						sha = sha[:7]
+					}
+					desc = "Cached result from " + sha
+				}
+				if !cfg.Silent {
+					fmt.Fprintf(os.Stderr, "%s (cached %s), skipping command\n", desc, entry.CreatedAt.Format(time.RFC3339))
+				}
+				skip(desc)
+				os.Exit(0)
+			}
+			cacheKey = key
+		}
+	}
 
 	// 3. Set Running Status
 	pending := base
 	pending.State = forge.StateRunning
 	pending.Description = cfg.PendingDesc
-	postStatus(ctx, client, commit, cfg.Silent, pending, "pending")
+	stopPending := delayedPost(cfg.MinPendingDelay, func() { post(pending, "pending") })
 
 	// 5. Execute Command
+	policy := executor.RetryPolicy{
+		Retries: cfg.Retries,
+		Delay:   cfg.RetryDelay,
+		// Timeouts only retry when asked for explicitly (--retry-on-codes 124).
+		RetryTimeouts: cfg.RetryOnCodes.Contains(executor.ExitCodeTimeout),
+		ShouldRetry: func(code int) bool {
+			if len(cfg.RetryOnCodes) > 0 {
+				return cfg.RetryOnCodes.Contains(code)
+			}
+			state, _ := finalStatus(code, nil, cfg)
+			return state == forge.StateFailure
+		},
+		OnRetry: func(attempt, total, lastExitCode int) {
+			// The retry status replaces a pending post that has not gone out yet.
+			stopPending()
+			if !cfg.Silent {
+				fmt.Fprintf(os.Stderr, "Warning: attempt %d/%d exited with code %d, retrying\n", attempt-1, total, lastExitCode)
+			}
+			retrying := base
+			retrying.State = forge.StateRunning
+			retrying.Description = fmt.Sprintf("Retry %d/%d…", attempt, total)
+			post(retrying, "retry")
+		},
+	}
 	exec := executor.New()
-	exitCode, err := exec.Run(ctx, cfg.Timeout, cfg.Command, cfg.Args)
+	exec.PTY = cfg.PTY
+	exec.PTYCols = cfg.PTYCols
+	exec.PTYRows = cfg.PTYRows
+	exec.Prefix = outputLabel(cfg.Prefix, cfg.Timestamps, cfg.ContextName)
+	exec.Timestamps = cfg.Timestamps
+	exec.Limits = executor.Limits{MemoryBytes: int64(cfg.MemoryLimit), CPUs: cfg.CPULimit}
+	exec.OnLimitsUnavailable = func(err error) {
+		if !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "Warning: %v; running without --memory-limit/--cpu-limit\n", err)
+		}
+	}
+	// A channel update supersedes the running post still waiting for
+	// --min-pending-delay, which must not overwrite it later.
+	statusEnv, stopChannel := startStatusChannel(ctx, client, commit, cfg.Silent, pending, cfg.SinkFlags, stopPending)
+	exec.Shell = cfg.Shell != ""
+	exec.Dir = cfg.Cwd
+	// User-supplied variables come last so they can override CI_STATUS_*.
+	exec.Env = append(statusEnv, env...)
+	result, err := exec.RunWithRetry(ctx, cfg.Timeout, policy, cfg.Command, cfg.Args)
+	stopChannel()
+	stopPending()
+	exitCode := result.ExitCode
+	rep := report.New(cfg.ContextName, commit, append([]string{cfg.Command}, cfg.Args...), result)
+	rep.Forge = forge.Name(client)
 
 	// Handle timeout specifically
 	if errors.Is(err, executor.ErrTimeout) {
 		timeoutOpts := base
 		timeoutOpts.State = forge.StateError
 		timeoutOpts.Description = "Timed out"
-		postStatus(ctx, client, commit, cfg.Silent, timeoutOpts, "timeout")
+		post(timeoutOpts, "timeout")
+		rep.State = string(forge.StateError)
+		rep.Description = timeoutOpts.Description
+		writeReport(rep, postErrors, cfg, true)
 		// Match final/start paths and --silent ("on errors"): still exit 124.
 		if !cfg.Silent {
 			fmt.Fprintln(os.Stderr, "Error: command timed out")
@@ -114,13 +348,49 @@ func execute(ctx context.Context, cfg config.Config) error {
 		os.Exit(executor.ExitCodeTimeout)
 	}
 
+	if errors.Is(err, executor.ErrOutOfMemory) {
+		oomOpts := base
+		oomOpts.State = forge.StateError
+		oomOpts.Description = "Killed: out of memory"
+		post(oomOpts, "final")
+		rep.State = string(forge.StateError)
+		rep.Description = oomOpts.Description
+		writeReport(rep, postErrors, cfg, true)
+		if !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "Error: command killed: out of memory (--memory-limit %s)\n", cfg.MemoryLimit.String())
+		}
+		if cfg.NormalizeExitCode {
+			os.Exit(1)
+		}
+		os.Exit(exitCode)
+	}
+
 	// 6. Set Final Status — do not shadow executor err: start failures return
 	// exitCode 0 with a non-nil error, and the exit path below must still see it.
-	state, desc := finalStatus(exitCode, err, cfg.SuccessDesc, cfg.FailureDesc)
+	state, desc := finalStatus(exitCode, err, cfg)
+	rep.State = string(state)
+	if rendered, renderErr := report.Render(desc, rep); renderErr != nil {
+		if !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", renderErr)
+		}
+	} else {
+		desc = rendered
+	}
 	finalOpts := base
 	finalOpts.State = state
-	finalOpts.Description = desc
-	postStatus(ctx, client, commit, cfg.Silent, finalOpts, "final")
+	finalOpts.Description = withRetries(desc, result.Retries())
+	post(finalOpts, "final")
+	rep.Description = finalOpts.Description
+	// A command that failed to start still gets its report (state error).
+	writeReport(rep, postErrors, cfg, err == nil)
+
+	// Only real passes are cached; a skip code means the check did not apply.
+	if cacheKey != "" && state == forge.StateSuccess && err == nil && !cfg.SkipCodes.Contains(exitCode) {
+		entry := cache.Entry{Key: cacheKey, Context: cfg.ContextName, Commit: commit, CreatedAt: time.Now()}
+		if storeErr := resultCache.Store(entry); storeErr != nil && !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", storeErr)
+		}
+	}
 
 	// 7. Exit
 	if err != nil && exitCode == 0 {
@@ -130,6 +400,13 @@ func execute(ctx context.Context, cfg config.Config) error {
 		}
 		os.Exit(1)
 	}
+	if cfg.NormalizeExitCode {
+		// Skipped codes post StateSuccess, so they normalize to 0 as well.
+		exitCode = 1
+		if state == forge.StateSuccess {
+			exitCode = 0
+		}
+	}
 	os.Exit(exitCode)
 	return nil
 }
@@ -139,12 +416,70 @@ func execute(ctx context.Context, cfg config.Config) error {
 // executor.Run returns exitCode 0 with a non-nil error when the process never
 // started (e.g. executable not found). That is a runtime/config problem
 // (StateError), not a failed check (StateFailure, reserved for real exit codes).
-func finalStatus(exitCode int, err error, successDesc, failureDesc string) (forge.State, string) {
-	if exitCode == 0 && err == nil {
-		return forge.StateSuccess, successDesc
-	}
+//
+// Real exit codes are matched against the configured lists in the order
+// skip, error, success, so the special-purpose lists win when they overlap
+// the success list. Anything unmatched is a failure.
+func finalStatus(exitCode int, err error, cfg config.Config) (forge.State, string) {
 	if err != nil && exitCode == 0 {
 		return forge.StateError, "Failed to start"
 	}
-	return forge.StateFailure, failureDesc
+	switch {
+	case cfg.SkipCodes.Contains(exitCode):
+		return forge.StateSuccess, cfg.SkipDesc
+	case cfg.ErrorCodes.Contains(exitCode):
+		return forge.StateError, cfg.ErrorDesc
+	case cfg.SuccessCodes.Contains(exitCode):
+		return forge.StateSuccess, cfg.SuccessDesc
+	default:
+		return forge.StateFailure, cfg.FailureDesc
+	}
+}
+
+// commandEnv merges --env-file files (in order) and --env entries, later
+// entries winning, into the extra environment for the command.
+func commandEnv(cfg config.Config) ([]string, error) {
+	var env []string
+	for _, path := range cfg.EnvFiles {
+		vars, err := executor.LoadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, vars...)
+	}
+	vars, err := executor.ParseEnv(cfg.Env)
+	if err != nil {
+		return nil, err
+	}
+	return append(env, vars...), nil
+}
+
+// writeReport emits the --report-json file, on every exit path so later
+// steps never read a stale one, and the --print-usage line for a command
+// that ran. Failures to write are warnings: the status is already set.
+func writeReport(rep report.Report, postErrors []report.PostError, cfg config.Config, ran bool) {
+	rep.PostErrors = postErrors
+	if cfg.PrintUsage && ran {
+		fmt.Fprintln(os.Stderr, rep.Summary())
+	}
+	if cfg.ReportJSON == "" {
+		return
+	}
+	if err := rep.WriteJSON(cfg.ReportJSON); err != nil && !cfg.Silent {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// withRetries appends the retry count to a final description so a green
+// check that needed retries is distinguishable from a clean pass
+// ("Passed after 2 retries").
+func withRetries(desc string, retries int) string {
+	switch retries {
+	case 0:
+		return desc
+	case 1:
+		return desc + " after 1 retry"
+	default:
+		return fmt.Sprintf("%s after %d retries", desc, retries)
+	}
 }
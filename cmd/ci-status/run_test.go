@@ -4,14 +4,27 @@ import (
 	"errors"
 	"testing"
 
+	"ci-status/internal/config"
 	"ci-status/internal/forge"
 )
 
 func TestFinalStatus(t *testing.T) {
+	base := config.Config{
+		SuccessDesc:  "Passed",
+		FailureDesc:  "Failed",
+		ErrorDesc:    "Errored",
+		SkipDesc:     "Skipped",
+		SuccessCodes: config.ExitCodes{{Min: 0, Max: 0}},
+	}
+	mapped := base
+	mapped.ErrorCodes = config.ExitCodes{{Min: 2, Max: 2}}
+	mapped.SkipCodes = config.ExitCodes{{Min: 78, Max: 78}}
+
 	tests := []struct {
 		name      string
 		exitCode  int
 		err       error
+		cfg       config.Config
 		wantState forge.State
 		wantDesc  string
 	}{
@@ -19,6 +32,7 @@ func TestFinalStatus(t *testing.T) {
 			name:      "success",
 			exitCode:  0,
 			err:       nil,
+			cfg:       base,
 			wantState: forge.StateSuccess,
 			wantDesc:  "Passed",
 		},
@@ -26,6 +40,7 @@ func TestFinalStatus(t *testing.T) {
 			name:      "non-zero exit is failure",
 			exitCode:  2,
 			err:       nil,
+			cfg:       base,
 			wantState: forge.StateFailure,
 			wantDesc:  "Failed",
 		},
@@ -33,6 +48,7 @@ func TestFinalStatus(t *testing.T) {
 			name:      "start failure is error not failure",
 			exitCode:  0,
 			err:       errors.New("start command: exec: \"nope\": executable file not found"),
+			cfg:       base,
 			wantState: forge.StateError,
 			wantDesc:  "Failed to start",
 		},
@@ -42,14 +58,58 @@ func TestFinalStatus(t *testing.T) {
 			name:      "non-zero with error still failure",
 			exitCode:  124,
 			err:       errors.New("command timed out"),
+			cfg:       base,
+			wantState: forge.StateFailure,
+			wantDesc:  "Failed",
+		},
+		{
+			name:      "error code maps to error",
+			exitCode:  2,
+			cfg:       mapped,
+			wantState: forge.StateError,
+			wantDesc:  "Errored",
+		},
+		{
+			name:      "skip code posts success with skip description",
+			exitCode:  78,
+			cfg:       mapped,
+			wantState: forge.StateSuccess,
+			wantDesc:  "Skipped",
+		},
+		{
+			name:      "unmapped code stays failure",
+			exitCode:  1,
+			cfg:       mapped,
 			wantState: forge.StateFailure,
 			wantDesc:  "Failed",
 		},
+		{
+			name:     "custom success codes replace the default",
+			exitCode: 0,
+			cfg: config.Config{
+				SuccessDesc:  "Passed",
+				FailureDesc:  "Failed",
+				SuccessCodes: config.ExitCodes{{Min: 1, Max: 3}},
+			},
+			wantState: forge.StateFailure,
+			wantDesc:  "Failed",
+		},
+		{
+			name:     "success range",
+			exitCode: 3,
+			cfg: config.Config{
+				SuccessDesc:  "Passed",
+				FailureDesc:  "Failed",
+				SuccessCodes: config.ExitCodes{{Min: 1, Max: 3}},
+			},
+			wantState: forge.StateSuccess,
+			wantDesc:  "Passed",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			state, desc := finalStatus(tt.exitCode, tt.err, "Passed", "Failed")
+			state, desc := finalStatus(tt.exitCode, tt.err, tt.cfg)
 			if state != tt.wantState {
 				t.Fatalf("state = %q, want %q", state, tt.wantState)
 			}
@@ -59,3 +119,37 @@ func TestFinalStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestWithRetries(t *testing.T) {
+	tests := []struct {
+		retries int
+		want    string
+	}{
+		{0, "Passed"},
+		{1, "Passed after 1 retry"},
+		{2, "Passed after 2 retries"},
+	}
+	for _, tt := range tests {
+		if got := withRetries("Passed", tt.retries); got != tt.want {
+			t.Errorf("withRetries(Passed, %d) = %q, want %q", tt.retries, got, tt.want)
+		}
+	}
+}
+
+func TestOutputLabel(t *testing.T) {
+	tests := []struct {
+		prefix     string
+		timestamps bool
+		want       string
+	}{
+		{"", false, ""},
+		{"", true, "lint"},
+		{contextPlaceholder, false, "lint"},
+		{"ci/{context}", true, "ci/lint"},
+	}
+	for _, tt := range tests {
+		if got := outputLabel(tt.prefix, tt.timestamps, "lint"); got != tt.want {
+			t.Errorf("outputLabel(%q, %t) = %q, want %q", tt.prefix, tt.timestamps, got, tt.want)
+		}
+	}
+}
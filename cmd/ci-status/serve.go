@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"ci-status/internal/config"
+	"ci-status/internal/daemon"
+	"ci-status/internal/executor"
+	"ci-status/internal/forge"
+	"github.com/spf13/cobra"
+)
+
+// ServeConfig holds the configuration for the 'serve' command.
+type ServeConfig struct {
+	// Socket is the Unix socket path to listen on.
+	Socket string
+	// SinkFlags, ForgePolicy and Commit override detection, as in run and set.
+	config.SinkFlags
+	ForgePolicy config.ForgePolicy
+	Commit      string
+	// Interval is how long updates to one context are coalesced.
+	Interval time.Duration
+	// DryRun prints the status requests instead of sending them.
+	DryRun bool
+	// Silent suppresses warnings.
+	Silent bool
+}
+
+var serveConfig ServeConfig
+
+var ErrSocketMissing = errors.New("--socket is required")
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve --socket path",
+	Short: "Post statuses sent by 'set --socket' from one background process",
+	Long: `Post statuses sent by 'set --socket' from one background process.
+
+The forge client and commit are detected once; 'ci-status set --socket path'
+(or CI_STATUS_SOCKET=path) then hands its status to the daemon instead of
+detecting and connecting itself. Rapid updates to the same context are
+coalesced for --interval and repeats of the last posted status are dropped
+per forge.
+Failed posts are retried with backoff, waiting out forge rate limits. On
+SIGINT/SIGTERM the daemon posts everything still queued, retrying for up to
+30s, so final states are not lost, then exits:
+
+  ci-status serve --socket /tmp/ci-status.sock &
+  export CI_STATUS_SOCKET=/tmp/ci-status.sock
+  ci-status set step-1 --state success
+  kill %1; wait
+
+Clients speak one JSON object per line:
+  {"context": "lint", "state": "success", "description": "...", "url": "...", "commit": "..."}
+and each gets {"ok": true} or {"ok": false, "error": "..."} back.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := applySettings(cmd, ""); err != nil {
+			return err
+		}
+		return executeServe(cmd.Context(), serveConfig)
+	},
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&serveConfig.Socket, "socket", "", "Unix socket path to listen on")
+	serveConfig.SinkFlags.Register(ServeCmd.Flags())
+	ServeCmd.Flags().Var(&serveConfig.ForgePolicy, "forge-policy", "With several --forge values, whether all or any of them must accept a status")
+	ServeCmd.Flags().StringVar(&serveConfig.Commit, "commit", "", "Override commit SHA (clients may still send their own)")
+	ServeCmd.Flags().DurationVar(&serveConfig.Interval, "interval", daemon.DefaultInterval, "Coalesce updates to the same context for this long before posting")
+	ServeCmd.Flags().BoolVar(&serveConfig.DryRun, "dry-run", false, "Print the status requests (token masked) to stderr instead of sending them; works outside CI")
+	ServeCmd.Flags().BoolVar(&serveConfig.Silent, "silent", false, "Suppress warnings")
+
+	documentEnv(ServeCmd)
+	Command.AddCommand(ServeCmd)
+}
+
+// executeServe runs the daemon until an interrupt signal or ctx cancel.
+//
+// Flow:
+//  1. Detects the forge client and commit once (via initForge, or
+//     initDryRunForge with --dry-run); its per-sink DedupClient drops
+//     repeats of posted statuses. Outside CI updates are accepted and
+//     dropped, like set.
+//  2. Listens on --socket and queues updates from clients.
+//  3. On shutdown posts the remaining queue and removes the socket.
+func executeServe(ctx context.Context, cfg ServeConfig) error {
+	if cfg.Socket == "" {
+		return quiet(ErrSocketMissing, cfg.Silent)
+	}
+
+	target := cfg.Sink()
+	var client forge.ForgeClient
+	var commit string
+	if cfg.DryRun {
+		client, commit = initDryRunForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Commit, cfg.Silent, os.Stderr)
+	} else {
+		client, commit = initForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Commit, cfg.Silent)
+	}
+
+	l, err := daemon.Listen(cfg.Socket)
+	if err != nil {
+		return quiet(err, cfg.Silent)
+	}
+	srv := &daemon.Server{
+		Client:   client,
+		Commit:   commit,
+		Interval: cfg.Interval,
+		OnError: func(opts forge.StatusOpts, err error) {
+			if !cfg.Silent {
+				fmt.Fprintf(os.Stderr, "Warning: failed to set %s status: %v\n", opts.Context, err)
+			}
+		},
+	}
+
+	ctx, stop := executor.WithSignalCancel(ctx)
+	defer stop()
+	if !cfg.Silent {
+		fmt.Fprintf(os.Stderr, "Listening on %s\n", cfg.Socket)
+	}
+	return quiet(srv.Serve(ctx, l), cfg.Silent)
+}
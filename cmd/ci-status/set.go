@@ -2,8 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 
+	"ci-status/internal/config"
+	"ci-status/internal/daemon"
 	"ci-status/internal/forge"
 	"github.com/spf13/cobra"
 )
@@ -23,20 +29,69 @@ type SetConfig struct {
 	Commit string
 	// PR overrides the detected PR number.
 	PR string
-	// Forge overrides the detected forge type.
-	Forge string
+	// SinkFlags choose the forge and credentials statuses are reported
+	// with; ForgePolicy applies to several --forge values.
+	config.SinkFlags
+	ForgePolicy config.ForgePolicy
+	// Batch is a JSON lines file ("-" for stdin) of statuses to post
+	// instead of a single context.
+	Batch string
+	// Socket hands statuses to a 'ci-status serve' daemon instead of
+	// detecting and posting directly.
+	Socket string
+	// Concurrency caps how many --batch statuses are posted at once.
+	Concurrency int
+	// Output is "text" (nothing on success) or "json" (a setResult on stdout).
+	Output string
+	// DryRun prints the request instead of sending it, even outside CI.
+	DryRun bool
 	// Silent suppresses warning/error messages.
 	Silent bool
 }
 
 var setConfig SetConfig
 
+// Values of --output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var ErrInvalidOutput = errors.New("invalid output format")
+
 var SetCmd = &cobra.Command{
-	Use:   "set [context-name]",
+	Use:   "set {context-name | --batch [file]}",
 	Short: "Set a status for a context",
-	Args:  cobra.ExactArgs(1),
+	Long: `Set a status for a context.
+
+With --batch, statuses are read as JSON lines from a file (or stdin with a
+bare --batch or --batch=-), one per line:
+
+  {"context": "shard-1", "state": "success", "description": "412 passed", "url": "https://..."}
+
+Missing fields default to the --state, --description, --url and --commit
+flags. Lines are posted concurrently (--concurrency), waiting when the forge
+rate-limits; every line is summarized and the exit code is non-zero if any
+line was invalid or could not be posted.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if setConfig.Batch != "" {
+			if len(args) > 0 {
+				return ErrBatchWithContext
+			}
+			// Contexts are per line, so only [defaults] applies.
+			if _, err := applySettings(cmd, ""); err != nil {
+				return err
+			}
+			return executeSet(cmd.Context(), setConfig)
+		}
+		if len(args) == 0 {
+			return ErrContextMissing
+		}
 		setConfig.ContextName = args[0]
+		if _, err := applySettings(cmd, setConfig.ContextName); err != nil {
+			return err
+		}
 		// cmd.Context() so a parent ExecuteContext cancel reaches status posts.
 		return executeSet(cmd.Context(), setConfig)
 	},
@@ -48,9 +103,17 @@ func init() {
 	SetCmd.Flags().StringVar(&setConfig.URL, "url", "", "Target URL")
 	SetCmd.Flags().StringVar(&setConfig.Commit, "commit", "", "Override commit SHA")
 	SetCmd.Flags().StringVar(&setConfig.PR, "pr", "", "Override pull request number")
-	SetCmd.Flags().StringVar(&setConfig.Forge, "forge", "", "Override automatic forge detection")
+	setConfig.SinkFlags.Register(SetCmd.Flags())
+	SetCmd.Flags().Var(&setConfig.ForgePolicy, "forge-policy", "With several --forge values, whether all or any of them must accept a status")
+	SetCmd.Flags().StringVar(&setConfig.Batch, "batch", "", "Post one status per JSON line of this file (bare --batch reads stdin)")
+	SetCmd.Flags().Lookup("batch").NoOptDefVal = "-"
+	SetCmd.Flags().IntVarP(&setConfig.Concurrency, "concurrency", "j", 8, "Maximum number of --batch statuses posted at once")
+	SetCmd.Flags().StringVar(&setConfig.Socket, "socket", "", "Send the status to the 'ci-status serve' daemon on this Unix socket")
+	SetCmd.Flags().StringVar(&setConfig.Output, "output", outputText, "Output format: text, or json to print what was posted (context, commit, forge, state, errors) to stdout")
+	SetCmd.Flags().BoolVar(&setConfig.DryRun, "dry-run", false, "Print the status request (token masked) instead of sending it; works outside CI")
 	SetCmd.Flags().BoolVar(&setConfig.Silent, "silent", false, "Suppress output")
 
+	documentEnv(SetCmd)
 	Command.AddCommand(SetCmd)
 }
 
@@ -66,35 +129,119 @@ func parseState(s string) (forge.State, error) {
 	}
 }
 
-// executeSet posts a forge status for the given context name.
+// setResult is the --output json document of 'set'.
+type setResult struct {
+	// Line is the input line number with --batch.
+	Line        int    `json:"line,omitempty"`
+	Context     string `json:"context"`
+	Commit      string `json:"commit,omitempty"`
+	Forge       string `json:"forge,omitempty"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url,omitempty"`
+	// Posted is true once the forge accepted the status (or --dry-run printed
+	// it).
+	Posted bool `json:"posted"`
+	// Queued is true once the --socket daemon accepted the status; it posts
+	// it later, so Posted stays false.
+	Queued bool `json:"queued,omitempty"`
+	DryRun bool `json:"dry_run,omitempty"`
+	// Skipped explains why nothing was posted without it being an error.
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// executeSet posts a forge status for the given context name and, with
+// --output json, prints a setResult to stdout (also when posting failed).
 // ctx should come from the cobra command (cmd.Context()) so a parent
 // ExecuteContext cancel reaches the status post.
 func executeSet(ctx context.Context, cfg SetConfig) error {
+	switch cfg.Output {
+	case "", outputText, outputJSON:
+	default:
+		return quiet(fmt.Errorf("%w %q (want %s|%s)", ErrInvalidOutput, cfg.Output, outputText, outputJSON), cfg.Silent)
+	}
+	if cfg.Batch != "" {
+		return executeBatch(ctx, cfg)
+	}
+
+	res, err := setStatus(ctx, cfg)
+	if cfg.Output == outputJSON {
+		if err != nil {
+			res.Error = err.Error()
+		}
+		if encErr := json.NewEncoder(os.Stdout).Encode(res); encErr != nil && err == nil {
+			err = encErr
+		}
+	}
+	return quiet(err, cfg.Silent)
+}
+
+// setStatus does the work of executeSet, describing what happened in the
+// returned setResult.
+func setStatus(ctx context.Context, cfg SetConfig) (setResult, error) {
+	res := setResult{
+		Context:     cfg.ContextName,
+		State:       cfg.State,
+		Description: cfg.Description,
+		TargetURL:   cfg.URL,
+		DryRun:      cfg.DryRun,
+	}
+
 	state, err := parseState(cfg.State)
 	if err != nil {
 		// Single print path is main; --silent still fails with exit 1, quietly.
-		return quiet(err, cfg.Silent)
+		return res, err
+	}
+
+	// The daemon has already detected its forge and commit (and applies the
+	// CI policy itself), so --socket skips detection entirely.
+	if cfg.Socket != "" {
+		client, err := daemon.Dial(ctx, cfg.Socket)
+		if err != nil {
+			return res, err
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+		res.Commit = cfg.Commit
+		if err := client.SetStatus(ctx, forge.StatusOpts{
+			Commit:      cfg.Commit,
+			Context:     cfg.ContextName,
+			State:       state,
+			Description: cfg.Description,
+			TargetURL:   cfg.URL,
+		}); err != nil {
+			return res, fmt.Errorf("failed to set status: %w", err)
+		}
+		res.Queued = true
+		return res, nil
 	}
 
 	// Outside CI, skip reporting (same policy as run). Unlike run, set's only
 	// job is to post a status — once we are in CI, failures must be errors so
-	// scripts do not treat a missed status as success.
-	if !isCI(cfg.Silent) {
-		return nil
+	// scripts do not treat a missed status as success. --dry-run reports
+	// anywhere, since nothing is sent.
+	if !cfg.DryRun && !isCI(cfg.Silent) {
+		res.Skipped = "CI environment variable not set"
+		return res, nil
 	}
 
-	client, err := forge.DetectClient(cfg.Forge)
+	client, err := setClient(cfg)
 	if err != nil {
-		return quiet(err, cfg.Silent)
+		return res, err
 	}
 
 	commit, err := forge.DetectCommit(cfg.Commit)
 	if err != nil {
-		return quiet(fmt.Errorf("commit not available: %w", err), cfg.Silent)
+		return res, fmt.Errorf("commit not available: %w", err)
 	}
 	if commit == "" {
-		return quiet(fmt.Errorf("commit not available"), cfg.Silent)
+		return res, fmt.Errorf("commit not available")
 	}
+	res.Commit = commit
+
+	res.Forge = forge.Name(client)
 
 	if err := client.SetStatus(ctx, forge.StatusOpts{
 		Commit:      commit,
@@ -103,8 +250,35 @@ func executeSet(ctx context.Context, cfg SetConfig) error {
 		Description: cfg.Description,
 		TargetURL:   cfg.URL,
 	}); err != nil {
-		return quiet(fmt.Errorf("failed to set status: %w", err), cfg.Silent)
+		return res, fmt.Errorf("failed to set status: %w", err)
 	}
+	res.Posted = true
+
+	return res, nil
+}
 
-	return nil
+// setClient detects the forge client for set and set --batch. Detection
+// failures are errors, except for forges left out under --forge-policy any
+// and with --dry-run, where they are warnings and the client prints its
+// requests instead of sending them.
+func setClient(cfg SetConfig) (forge.ForgeClient, error) {
+	target := cfg.Sink()
+	client, err := detectForge(cfg.Forge, target, cfg.ForgePolicy, cfg.Silent)
+	if err != nil {
+		if !cfg.DryRun && (client == nil || cfg.ForgePolicy.RequireAll()) {
+			return nil, err
+		}
+		if !cfg.Silent {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if cfg.DryRun {
+		// Keep stdout for the JSON document.
+		var out io.Writer = os.Stdout
+		if cfg.Output == outputJSON {
+			out = os.Stderr
+		}
+		client = dryRun(client, out)
+	}
+	return client, nil
 }
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"ci-status/internal/credentials/credentialstest"
+	"ci-status/internal/daemon"
 	"ci-status/internal/forge"
 )
 
@@ -49,7 +54,7 @@ func TestExecuteSet_NotCI_Noop(t *testing.T) {
 
 func TestExecuteSet_CI_MissingToken(t *testing.T) {
 	t.Setenv("CI", "true")
-	t.Setenv("GITHUB_TOKEN", "")
+	credentialstest.Isolate(t)
 	// Force a github-ish remote via git is hard; DetectClient uses real git remote
 	// of this checkout (github.com/lucasew/ci-status), so missing token must error.
 	err := executeSet(t.Context(), SetConfig{
@@ -117,3 +122,84 @@ func TestExecuteSet_InvalidState_NotSilent(t *testing.T) {
 		t.Fatal("non-silent invalid state must not be quiet")
 	}
 }
+
+func TestExecuteSet_DryRunOutsideCI(t *testing.T) {
+	t.Setenv("CI", "")
+	credentialstest.Isolate(t)
+
+	err := executeSet(t.Context(), SetConfig{
+		ContextName: "lint",
+		State:       "success",
+		Commit:      "abc123",
+		DryRun:      true,
+		Silent:      true,
+	})
+	if err != nil {
+		t.Fatalf("dry run should not need CI or a token: %v", err)
+	}
+}
+
+func TestSetStatus_ResultDescribesOutcome(t *testing.T) {
+	t.Setenv("CI", "")
+	res, err := setStatus(t.Context(), SetConfig{ContextName: "lint", State: "success", Silent: true})
+	if err != nil {
+		t.Fatalf("setStatus: %v", err)
+	}
+	if res.Posted || res.Skipped == "" {
+		t.Fatalf("outside CI the result should be skipped, got %+v", res)
+	}
+
+	credentialstest.Isolate(t)
+	res, err = setStatus(t.Context(), SetConfig{ContextName: "lint", State: "success", Commit: "abc123", DryRun: true, Silent: true})
+	if err != nil {
+		t.Fatalf("setStatus: %v", err)
+	}
+	if !res.Posted || !res.DryRun || res.Commit != "abc123" {
+		t.Fatalf("dry run result = %+v", res)
+	}
+}
+
+func TestExecuteSet_InvalidOutput(t *testing.T) {
+	err := executeSet(t.Context(), SetConfig{ContextName: "lint", State: "success", Output: "yaml"})
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("err = %v, want ErrInvalidOutput", err)
+	}
+}
+
+func TestExecuteSet_Socket(t *testing.T) {
+	t.Setenv("CI", "")
+	dir, err := os.MkdirTemp("", "cis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "d.sock")
+
+	l, err := daemon.Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &recordingClient{}
+	srv := &daemon.Server{Client: client, Commit: "abc"}
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, l) }()
+
+	// Outside CI too: the daemon applies the policy, not the client.
+	res, err := setStatus(t.Context(), SetConfig{ContextName: "lint", State: "success", Description: "ok", Socket: path})
+	if err != nil {
+		t.Fatalf("setStatus: %v", err)
+	}
+	// The daemon has only queued the status.
+	if !res.Queued || res.Posted {
+		t.Errorf("result = %+v, want queued and not posted", res)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	want := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateSuccess, Description: "ok"}
+	if got := client.snapshot(); len(got) != 1 || got[0] != want {
+		t.Fatalf("posts = %+v, want %+v", got, want)
+	}
+}
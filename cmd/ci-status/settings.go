@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ci-status/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// sourceDefault is the source 'config show' reports for built-in defaults.
+const sourceDefault = "default"
+
+// envPrefix starts the environment variable bound to every flag:
+// --pending-desc is CI_STATUS_PENDING_DESC.
+const envPrefix = "CI_STATUS_"
+
+// precedenceHelp is appended to the long help of commands using applySettings.
+const precedenceHelp = `Every flag can also be set with a CI_STATUS_* environment variable (shown
+next to each flag) or in a config file (see 'ci-status config'). Precedence:
+command-line flags > environment variables > config files > built-in defaults.`
+
+// applySettings fills the flags the user did not give on the command line,
+// first from CI_STATUS_* environment variables, then from config files. It
+// returns the source of every flag it set, keyed by flag name.
+func applySettings(cmd *cobra.Command, contextName string) (map[string]string, error) {
+	sources, err := applyEnv(cmd)
+	if err != nil {
+		return nil, err
+	}
+	fileSources, err := applyConfigFiles(cmd, contextName)
+	if err != nil {
+		return nil, err
+	}
+	for name, source := range fileSources {
+		sources[name] = source
+	}
+	return sources, nil
+}
+
+// envName is the environment variable bound to a flag.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets every flag not given on the command line from its
+// CI_STATUS_* variable. Empty variables are ignored so a variable declared
+// but left blank in CI YAML does not override anything. Values use the flag syntax (durations like
+// "5m", booleans like "true"); errors name the variable.
+func applyEnv(cmd *cobra.Command) (map[string]string, error) {
+	sources := map[string]string{}
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" {
+			return
+		}
+		name := envName(flag.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if setErr := flag.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+			return
+		}
+		flag.Changed = true
+		sources[flag.Name] = "env " + name
+	})
+	return sources, err
+}
+
+// documentEnv appends each flag's environment variable to its usage text
+// and the precedence rules to the long help.
+func documentEnv(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		flag.Usage += " [$" + envName(flag.Name) + "]"
+	})
+	if cmd.Long == "" {
+		cmd.Long = cmd.Short + "."
+	}
+	cmd.Long += "\n\n" + precedenceHelp
+}
+
+// applyConfigFiles fills cmd's flags that were not given on the command line
+// from the user and project config files (see config.FindFiles), using the
+// per-context overrides for contextName. Precedence is flags > env > file >
+// built-in default; flags already set by a higher layer are left alone.
+//
+// It returns the source of every flag it set, keyed by flag name. Keys that
+// belong to other commands are skipped; keys no command knows are errors.
+func applyConfigFiles(cmd *cobra.Command, contextName string) (map[string]string, error) {
+	paths, err := config.FindFiles()
+	if err != nil {
+		return nil, err
+	}
+	files, err := config.LoadFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(files, isKnownFlag); err != nil {
+		return nil, err
+	}
+
+	sources := map[string]string{}
+	for name, setting := range config.Resolve(files, contextName) {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if err := setFlag(flag, config.FlagValues(setting.Value)); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", setting.Source, name, err)
+		}
+		sources[name] = setting.Source
+	}
+	return sources, nil
+}
+
+// setFlag assigns values to flag as if given on the command line. List flags
+// take one value per element; other flags get the values joined by commas,
+// which is what the exit code lists expect.
+func setFlag(flag *pflag.Flag, values []string) error {
+	if list, ok := flag.Value.(pflag.SliceValue); ok {
+		if err := list.Replace(values); err != nil {
+			return err
+		}
+	} else if err := flag.Value.Set(strings.Join(values, ",")); err != nil {
+		return err
+	}
+	flag.Changed = true
+	return nil
+}
+
+// isKnownFlag reports whether any subcommand defines a flag called name.
+func isKnownFlag(name string) bool {
+	for _, c := range Command.Commands() {
+		if c.Flags().Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
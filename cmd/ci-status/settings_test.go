@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfigFilesKeepsExplicitFlags(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "[defaults]\ntimeout = \"5m\"\nsuccess-desc = \"From file\"\npaths = [\"a/**\", \"b\"]\n[contexts.\"lint\"]\nretries = 2\n"
+	if err := os.WriteFile(filepath.Join(dir, ".ci-status.toml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	var timeout time.Duration
+	var desc string
+	var retries int
+	var paths []string
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "")
+	cmd.Flags().StringVar(&desc, "success-desc", "Passed", "")
+	cmd.Flags().IntVar(&retries, "retries", 0, "")
+	cmd.Flags().StringSliceVar(&paths, "paths", nil, "")
+	if err := cmd.Flags().Parse([]string{"--success-desc", "From flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := applyConfigFiles(cmd, "lint")
+	if err != nil {
+		t.Fatalf("applyConfigFiles: %v", err)
+	}
+	if timeout != 5*time.Minute || retries != 2 || len(paths) != 2 || paths[1] != "b" {
+		t.Fatalf("file values not applied: timeout=%v retries=%d paths=%q", timeout, retries, paths)
+	}
+	if desc != "From flag" {
+		t.Fatalf("explicit flag overwritten: %q", desc)
+	}
+	if _, ok := sources["success-desc"]; ok {
+		t.Fatal("explicit flag reported as coming from the file")
+	}
+	if sources["retries"] == "" {
+		t.Fatalf("missing source for retries: %v", sources)
+	}
+}
+
+func TestApplySettingsPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	if err := os.WriteFile(filepath.Join(dir, ".ci-status.toml"), []byte("[defaults]\ntimeout = \"5m\"\nforge = \"github\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	t.Setenv("CI_STATUS_TIMEOUT", "2m")
+	t.Setenv("CI_STATUS_SILENT", "true")
+	t.Setenv("CI_STATUS_FORGE", "")
+
+	var timeout time.Duration
+	var silent bool
+	var forgeName, desc string
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "")
+	cmd.Flags().BoolVar(&silent, "silent", false, "")
+	cmd.Flags().StringVar(&forgeName, "forge", "", "")
+	cmd.Flags().StringVar(&desc, "success-desc", "Passed", "")
+	t.Setenv("CI_STATUS_SUCCESS_DESC", "From env")
+	if err := cmd.Flags().Parse([]string{"--success-desc", "From flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := applySettings(cmd, "")
+	if err != nil {
+		t.Fatalf("applySettings: %v", err)
+	}
+	if timeout != 2*time.Minute || !silent || forgeName != "github" || desc != "From flag" {
+		t.Fatalf("got timeout=%v silent=%v forge=%q desc=%q", timeout, silent, forgeName, desc)
+	}
+	if sources["timeout"] != "env CI_STATUS_TIMEOUT" {
+		t.Fatalf("timeout source = %q", sources["timeout"])
+	}
+}
+
+func TestApplyEnvNamesVariableOnError(t *testing.T) {
+	t.Setenv("CI_STATUS_TIMEOUT", "soon")
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().Duration("timeout", 0, "")
+	_, err := applyEnv(cmd)
+	if err == nil || !strings.Contains(err.Error(), "CI_STATUS_TIMEOUT") {
+		t.Fatalf("err = %v, want it to name CI_STATUS_TIMEOUT", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package cache remembers successful runs keyed by a hash of their inputs so
+// identical checks (e.g. after a rebase) can be reported without rerunning.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"ci-status/internal/glob"
+)
+
+// ErrNoInputs is returned by Key when no file matches the patterns. A key
+// over zero files would hit for any tree, so callers should run normally.
+var ErrNoInputs = errors.New("no files match the cache key patterns")
+
+// Entry records one successful run.
+type Entry struct {
+	// Key is the input hash produced by Key.
+	Key string `json:"key"`
+	// Context is the status context that produced the entry.
+	Context string `json:"context"`
+	// Commit is the commit the successful run was reported on.
+	Commit string `json:"commit"`
+	// CreatedAt is when the entry was stored.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Cache is a directory of entries, one JSON file per key. The directory may
+// be shared between jobs (e.g. restored by a CI cache action).
+type Cache struct {
+	Dir string
+	// TTL expires entries older than this. Zero keeps entries forever.
+	TTL time.Duration
+}
+
+// DefaultDir is the per-user cache directory ($XDG_CACHE_HOME/ci-status on Linux).
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locate user cache dir: %w", err)
+	}
+	return filepath.Join(dir, "ci-status"), nil
+}
+
+// Key hashes every file under root matching patterns (slash-separated,
+// relative to root, "**" allowed), together with root itself (the command's
+// working directory), the context name, the command line and env (the
+// variables set for the command). File names are part of the hash so
+// renames invalidate it. The .git directory is never walked.
+func Key(root string, patterns []string, context, command string, args, env []string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if glob.MatchAny(patterns, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("walk cache inputs: %w", err)
+	}
+	if len(files) == 0 {
+		return "", ErrNoInputs
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	// NUL separators keep ("ab","c") and ("a","bc") from hashing the same.
+	writeField := func(s string) {
+		_, _ = io.WriteString(h, s)
+		_, _ = h.Write([]byte{0})
+	}
+	writeField(root)
+	writeField(context)
+	writeField(command)
+	for _, a := range args {
+		writeField(a)
+	}
+	// A count between the lists keeps an argument from passing for a
+	// variable.
+	writeField(fmt.Sprint(len(env)))
+	for _, kv := range env {
+		writeField(kv)
+	}
+	for _, rel := range files {
+		writeField(rel)
+		sum, err := hashFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", err
+		}
+		writeField(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("hash cache input: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash cache input: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Lookup returns the entry for key. ok is false when there is no entry or it
+// expired; unreadable entries are treated as misses so a corrupt cache never
+// fails a run.
+func (c Cache) Lookup(key string) (Entry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return Entry{}, false
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return Entry{}, false
+	}
+	if c.TTL > 0 && time.Since(e.CreatedAt) > c.TTL {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// Store writes e atomically (temp file + rename) so concurrent jobs sharing
+// the directory never read a half-written entry.
+func (c Cache) Store(e Entry) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal cache entry: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.Dir, e.Key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(e.Key)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	return nil
+}
+
+func (c Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
@@ -0,0 +1,115 @@
+package cache_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ci-status/internal/cache"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "go.sum", "sum")
+	writeFile(t, root, "cmd/main.go", "package main")
+	writeFile(t, root, "README.md", "docs")
+	writeFile(t, root, ".git/objects/x.go", "ignored")
+
+	patterns := []string{"go.sum", "**/*.go"}
+	k1, err := cache.Key(root, patterns, "test", "go", []string{"test", "./..."}, nil)
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+
+	// Unrelated files and .git do not affect the key.
+	writeFile(t, root, "README.md", "changed docs")
+	writeFile(t, root, ".git/objects/x.go", "changed")
+	k2, err := cache.Key(root, patterns, "test", "go", []string{"test", "./..."}, nil)
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+	if k1 != k2 {
+		t.Fatal("key changed although no input changed")
+	}
+
+	writeFile(t, root, "cmd/main.go", "package main // edited")
+	k3, _ := cache.Key(root, patterns, "test", "go", []string{"test", "./..."}, nil)
+	if k3 == k1 {
+		t.Fatal("key should change when an input file changes")
+	}
+
+	k4, _ := cache.Key(root, patterns, "test", "go", []string{"test", "-race", "./..."}, nil)
+	if k4 == k3 {
+		t.Fatal("key should change when the command line changes")
+	}
+	k5, _ := cache.Key(root, patterns, "lint", "go", []string{"test", "-race", "./..."}, nil)
+	if k5 == k4 {
+		t.Fatal("key should change when the context changes")
+	}
+	k6, _ := cache.Key(root, patterns, "lint", "go", []string{"test", "-race", "./..."}, []string{"GOFLAGS=-tags=e2e"})
+	if k6 == k5 {
+		t.Fatal("key should change when the environment changes")
+	}
+
+	// The same files in another working directory are another run.
+	other := t.TempDir()
+	writeFile(t, other, "go.sum", "sum")
+	writeFile(t, other, "cmd/main.go", "package main // edited")
+	k7, _ := cache.Key(other, patterns, "lint", "go", []string{"test", "-race", "./..."}, []string{"GOFLAGS=-tags=e2e"})
+	if k7 == k6 {
+		t.Fatal("key should change when the working directory changes")
+	}
+
+	if _, err := cache.Key(root, []string{"*.rs"}, "test", "go", nil, nil); !errors.Is(err, cache.ErrNoInputs) {
+		t.Fatalf("want ErrNoInputs, got %v", err)
+	}
+}
+
+func TestStoreLookup(t *testing.T) {
+	c := cache.Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+	if _, ok := c.Lookup("k"); ok {
+		t.Fatal("empty cache should miss")
+	}
+
+	entry := cache.Entry{Key: "k", Context: "test", Commit: "abc", CreatedAt: time.Now()}
+	if err := c.Store(entry); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	got, ok := c.Lookup("k")
+	if !ok {
+		t.Fatal("expected hit after Store")
+	}
+	if got.Commit != "abc" || got.Context != "test" {
+		t.Fatalf("entry = %+v", got)
+	}
+
+	c.TTL = time.Minute
+	old := entry
+	old.CreatedAt = time.Now().Add(-time.Hour)
+	if err := c.Store(old); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if _, ok := c.Lookup("k"); ok {
+		t.Fatal("expired entry should miss")
+	}
+
+	if err := os.WriteFile(filepath.Join(c.Dir, "bad.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Lookup("bad"); ok {
+		t.Fatal("corrupt entry should miss")
+	}
+}
@@ -0,0 +1,78 @@
+// Package changes decides whether a commit touched files relevant to a check,
+// so run can skip commands whose inputs did not change.
+package changes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"ci-status/internal/glob"
+)
+
+// ErrInvalidBase is returned for base refs git would parse as options.
+var ErrInvalidBase = errors.New("invalid base ref")
+
+// DetectBase resolves the ref to diff against.
+// It prioritizes the override value, then pull/merge request environment
+// variables (GitHub Actions, GitLab CI, Bitbucket Pipelines). Branch names
+// are prefixed with "origin/" because CI checkouts rarely have local branches
+// for the target. Returns "" when no base can be determined (e.g. a push
+// build), in which case callers should not skip anything.
+func DetectBase(override string) string {
+	if override != "" {
+		return override
+	}
+	// GitLab exposes the exact merge-base SHA; prefer it over the branch.
+	if sha := os.Getenv("CI_MERGE_REQUEST_DIFF_BASE_SHA"); sha != "" {
+		return sha
+	}
+	for _, env := range []string{"GITHUB_BASE_REF", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "BITBUCKET_PR_DESTINATION_BRANCH"} {
+		if branch := os.Getenv(env); branch != "" {
+			return "origin/" + branch
+		}
+	}
+	return ""
+}
+
+// Files lists paths changed between the merge base of base and HEAD, relative
+// to the repository root. Renames are reported as a delete plus an add so a
+// file moved out of a filtered directory still counts as a change there.
+//
+// A base starting with "-" is rejected with ErrInvalidBase: git would read
+// it as an option (e.g. "--output=file"), since base+"...HEAD" comes before
+// the "--" that ends them.
+func Files(ctx context.Context, base string) ([]string, error) {
+	if strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBase, base)
+	}
+	// "--" keeps a ref that is also a file name from being read as a path.
+	cmd := exec.CommandContext(ctx, "git", "diff", "--name-only", "--no-renames", "-z", base+"...HEAD", "--")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff against %s: %w: %s", base, err, strings.TrimSpace(stderr.String()))
+	}
+	var files []string
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// AnyMatch reports whether at least one file matches at least one pattern.
+func AnyMatch(patterns, files []string) bool {
+	for _, f := range files {
+		if glob.MatchAny(patterns, f) {
+			return true
+		}
+	}
+	return false
+}
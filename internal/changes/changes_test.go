@@ -0,0 +1,101 @@
+package changes_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ci-status/internal/changes"
+)
+
+func TestDetectBase(t *testing.T) {
+	for _, env := range []string{"CI_MERGE_REQUEST_DIFF_BASE_SHA", "GITHUB_BASE_REF", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "BITBUCKET_PR_DESTINATION_BRANCH"} {
+		t.Setenv(env, "")
+	}
+	if got := changes.DetectBase(""); got != "" {
+		t.Fatalf("DetectBase without env = %q, want empty", got)
+	}
+
+	t.Setenv("GITHUB_BASE_REF", "main")
+	if got := changes.DetectBase(""); got != "origin/main" {
+		t.Fatalf("DetectBase = %q, want origin/main", got)
+	}
+	if got := changes.DetectBase("v1.0"); got != "v1.0" {
+		t.Fatalf("override should win, got %q", got)
+	}
+
+	t.Setenv("CI_MERGE_REQUEST_DIFF_BASE_SHA", "abc123")
+	if got := changes.DetectBase(""); got != "abc123" {
+		t.Fatalf("GitLab diff base SHA should win, got %q", got)
+	}
+}
+
+func TestAnyMatch(t *testing.T) {
+	files := []string{"api/main.go", "README.md"}
+	if changes.AnyMatch([]string{"web/**"}, files) {
+		t.Fatal("web/** should not match backend changes")
+	}
+	if !changes.AnyMatch([]string{"web/**", "**/*.go"}, files) {
+		t.Fatal("**/*.go should match api/main.go")
+	}
+}
+
+func TestFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("README.md")
+	git("add", ".")
+	git("commit", "-qm", "base")
+	git("tag", "base")
+	write("web/app.ts")
+	git("add", ".")
+	git("commit", "-qm", "change")
+
+	t.Chdir(dir)
+	files, err := changes.Files(t.Context(), "base")
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if strings.Join(files, ",") != "web/app.ts" {
+		t.Fatalf("Files = %v, want [web/app.ts]", files)
+	}
+
+	if _, err := changes.Files(t.Context(), "no-such-ref"); err == nil {
+		t.Fatal("expected error for unknown base")
+	}
+	out := filepath.Join(dir, "written-by-git")
+	if _, err := changes.Files(t.Context(), "--output="+out); !errors.Is(err, changes.ErrInvalidBase) {
+		t.Fatalf("option-like base: %v, want ErrInvalidBase", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Fatal("git read the base as --output")
+	}
+}
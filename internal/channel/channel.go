@@ -0,0 +1,149 @@
+// Package channel implements the status channel: a file the wrapped command
+// appends "key: value" lines to ("description: 40/100 tests") so ci-status
+// can forward progress to the forge while the command runs.
+package channel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often the channel file is polled. Updates written
+// between polls are coalesced so chatty commands do not flood the forge API.
+const DefaultInterval = 2 * time.Second
+
+// Update is the latest state requested through the channel. Empty fields
+// keep their previous value.
+type Update struct {
+	Description string
+	TargetURL   string
+}
+
+// Parse reads one channel line. Recognized keys are "description" and
+// "url" (alias "target_url"); anything else is ignored so commands can
+// share the file with future keys.
+func Parse(line string) (Update, bool) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return Update{}, false
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Update{}, false
+	}
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "description":
+		return Update{Description: value}, true
+	case "url", "target_url":
+		return Update{TargetURL: value}, true
+	default:
+		return Update{}, false
+	}
+}
+
+// Channel is an open status channel file.
+type Channel struct {
+	// Path is exported to the command as CI_STATUS_CHANNEL.
+	Path string
+
+	offset  int64
+	partial []byte
+	current Update
+}
+
+// Create makes an empty channel file in the temp directory.
+func Create() (*Channel, error) {
+	f, err := os.CreateTemp("", "ci-status-channel-*")
+	if err != nil {
+		return nil, fmt.Errorf("create status channel: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("create status channel: %w", err)
+	}
+	return &Channel{Path: f.Name()}, nil
+}
+
+// Poll reads lines appended since the last call and merges them into the
+// current update. changed is false when nothing new was requested.
+// A trailing line without newline is kept until it is completed.
+func (c *Channel) Poll() (u Update, changed bool, err error) {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return c.current, false, fmt.Errorf("read status channel: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := f.Seek(c.offset, io.SeekStart); err != nil {
+		return c.current, false, fmt.Errorf("read status channel: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return c.current, false, fmt.Errorf("read status channel: %w", err)
+	}
+	c.offset += int64(len(data))
+
+	data = append(c.partial, data...)
+	last := bytes.LastIndexByte(data, '\n')
+	if last < 0 {
+		c.partial = data
+		return c.current, false, nil
+	}
+	c.partial = append([]byte(nil), data[last+1:]...)
+
+	next := c.current
+	for _, line := range strings.Split(string(data[:last]), "\n") {
+		upd, ok := Parse(strings.TrimRight(line, "\r"))
+		if !ok {
+			continue
+		}
+		if upd.Description != "" {
+			next.Description = upd.Description
+		}
+		if upd.TargetURL != "" {
+			next.TargetURL = upd.TargetURL
+		}
+	}
+	changed = next != c.current
+	c.current = next
+	return next, changed, nil
+}
+
+// Watch polls every interval and calls fn with each changed update, until
+// the returned stop func is called. stop waits for an in-flight fn, so no
+// update is delivered after it returns (callers post the final status next),
+// and removes the file.
+func (c *Channel) Watch(ctx context.Context, interval time.Duration, fn func(Update)) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if u, changed, err := c.Poll(); err == nil && changed {
+					fn(u)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+		_ = os.Remove(c.Path)
+	}
+}
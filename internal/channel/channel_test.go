@@ -0,0 +1,98 @@
+package channel_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"ci-status/internal/channel"
+)
+
+func appendTo(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want channel.Update
+		ok   bool
+	}{
+		{"description: 40/100 tests", channel.Update{Description: "40/100 tests"}, true},
+		{"Description:ratio: 1:2", channel.Update{Description: "ratio: 1:2"}, true},
+		{"url: https://ci/logs/1", channel.Update{TargetURL: "https://ci/logs/1"}, true},
+		{"target_url: https://ci/2", channel.Update{TargetURL: "https://ci/2"}, true},
+		{"description:", channel.Update{}, false},
+		{"progress: 10%", channel.Update{}, false},
+		{"just text", channel.Update{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := channel.Parse(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPollCoalescesAndKeepsPartialLines(t *testing.T) {
+	c, err := channel.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(c.Path) })
+
+	if _, changed, err := c.Poll(); err != nil || changed {
+		t.Fatalf("empty channel: changed=%v err=%v", changed, err)
+	}
+
+	appendTo(t, c.Path, "description: 1/3\ndescription: 2/3\nurl: https://x\ndescription: 3")
+	u, changed, err := c.Poll()
+	if err != nil || !changed {
+		t.Fatalf("Poll: changed=%v err=%v", changed, err)
+	}
+	if want := (channel.Update{Description: "2/3", TargetURL: "https://x"}); u != want {
+		t.Fatalf("got %+v, want %+v (latest complete lines only)", u, want)
+	}
+
+	appendTo(t, c.Path, "/3\n")
+	if u, changed, _ = c.Poll(); !changed || u.Description != "3/3" {
+		t.Fatalf("partial line not completed: %+v changed=%v", u, changed)
+	}
+
+	appendTo(t, c.Path, "description: 3/3\n")
+	if _, changed, _ = c.Poll(); changed {
+		t.Fatal("repeating the same description should not count as a change")
+	}
+}
+
+func TestWatchStopsAndRemovesFile(t *testing.T) {
+	c, err := channel.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan channel.Update, 10)
+	stop := c.Watch(t.Context(), 10*time.Millisecond, func(u channel.Update) { got <- u })
+
+	appendTo(t, c.Path, "description: halfway\n")
+	select {
+	case u := <-got:
+		if u.Description != "halfway" {
+			t.Fatalf("got %+v", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update delivered")
+	}
+
+	stop()
+	if _, err := os.Stat(c.Path); !os.IsNotExist(err) {
+		t.Fatalf("channel file still exists after stop: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes parsed from flags such as --memory-limit 4G.
+// Suffixes are binary (K = 1024) as is usual for memory limits; "KiB"/"KB"
+// style spellings are accepted too. It implements pflag.Value.
+type ByteSize int64
+
+var byteUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// ParseByteSize parses "512M", "1.5G", "4GiB" or a plain number of bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "ib"), "b")
+	num := strings.TrimRight(t, "kmgt")
+	unit, ok := byteUnits[t[len(num):]]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return ByteSize(n * float64(unit)), nil
+}
+
+// String renders the size with the largest exact binary suffix ("4G").
+func (b *ByteSize) String() string {
+	if b == nil || *b == 0 {
+		return "0"
+	}
+	n := int64(*b)
+	for _, u := range []string{"t", "g", "m", "k"} {
+		if n%byteUnits[u] == 0 {
+			return strconv.FormatInt(n/byteUnits[u], 10) + strings.ToUpper(u)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// Set implements pflag.Value.
+func (b *ByteSize) Set(s string) error {
+	v, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// Type implements pflag.Value.
+func (b *ByteSize) Type() string { return "size" }
@@ -0,0 +1,45 @@
+package config_test
+
+import (
+	"testing"
+
+	"ci-status/internal/config"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]config.ByteSize{
+		"1024": 1024,
+		"512M": 512 << 20,
+		"4G":   4 << 30,
+		"4gib": 4 << 30,
+		"1.5G": 3 << 29,
+		"2KB":  2048,
+	}
+	for in, want := range tests {
+		got, err := config.ParseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "G", "4X", "-1G", "4 G B"} {
+		if _, err := config.ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	for _, tc := range []struct {
+		size config.ByteSize
+		want string
+	}{
+		{0, "0"},
+		{4 << 30, "4G"},
+		{1536 << 20, "1536M"},
+		{1000, "1000"},
+	} {
+		if got := tc.size.String(); got != tc.want {
+			t.Errorf("String(%d) = %q, want %q", tc.size, got, tc.want)
+		}
+	}
+}
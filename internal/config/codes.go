@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CodeRange is an inclusive range of process exit codes. A single code is a
+// range with Min == Max.
+type CodeRange struct {
+	Min int
+	Max int
+}
+
+// ExitCodes is a list of exit code ranges parsed from flags such as
+// --success-codes "0,2-5,78". It implements pflag.Value so cobra flags can
+// bind it directly.
+type ExitCodes []CodeRange
+
+// ParseExitCodes parses a comma-separated list of codes and inclusive ranges
+// ("1", "2-5", "0,78"). Empty input yields an empty list. Negative codes and
+// reversed ranges are rejected so typos do not silently match nothing.
+func ParseExitCodes(s string) (ExitCodes, error) {
+	var codes ExitCodes
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		min, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit code %q", part)
+		}
+		max := min
+		if isRange {
+			max, err = strconv.Atoi(strings.TrimSpace(hi))
+			if err != nil {
+				return nil, fmt.Errorf("invalid exit code range %q", part)
+			}
+		}
+		if min < 0 || max < min {
+			return nil, fmt.Errorf("invalid exit code range %q", part)
+		}
+		codes = append(codes, CodeRange{Min: min, Max: max})
+	}
+	return codes, nil
+}
+
+// Contains reports whether code falls in any of the ranges.
+func (c ExitCodes) Contains(code int) bool {
+	for _, r := range c {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the list in the same syntax ParseExitCodes accepts.
+func (c *ExitCodes) String() string {
+	if c == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*c))
+	for _, r := range *c {
+		if r.Min == r.Max {
+			parts = append(parts, strconv.Itoa(r.Min))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d-%d", r.Min, r.Max))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set implements pflag.Value. It replaces the current list instead of
+// appending so --success-codes 0,1 means exactly {0,1}, not the default {0}
+// plus the new codes.
+func (c *ExitCodes) Set(s string) error {
+	parsed, err := ParseExitCodes(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Type implements pflag.Value.
+func (c *ExitCodes) Type() string { return "codes" }
@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"testing"
+
+	"ci-status/internal/config"
+)
+
+func TestParseExitCodes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		match   []int
+		noMatch []int
+		err     bool
+	}{
+		{in: "0", want: "0", match: []int{0}, noMatch: []int{1}},
+		{in: "0,78", want: "0,78", match: []int{0, 78}, noMatch: []int{1, 77}},
+		{in: " 2-5 , 137", want: "2-5,137", match: []int{2, 5, 137}, noMatch: []int{1, 6}},
+		{in: "", want: "", noMatch: []int{0}},
+		{in: "x", err: true},
+		{in: "5-2", err: true},
+		{in: "-1", err: true},
+		{in: "1-", err: true},
+	}
+
+	for _, tt := range tests {
+		codes, err := config.ParseExitCodes(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseExitCodes(%q) expected error, got %v", tt.in, codes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseExitCodes(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got := codes.String(); got != tt.want {
+			t.Errorf("ParseExitCodes(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		for _, c := range tt.match {
+			if !codes.Contains(c) {
+				t.Errorf("ParseExitCodes(%q) should contain %d", tt.in, c)
+			}
+		}
+		for _, c := range tt.noMatch {
+			if codes.Contains(c) {
+				t.Errorf("ParseExitCodes(%q) should not contain %d", tt.in, c)
+			}
+		}
+	}
+}
+
+func TestExitCodesSetReplacesDefault(t *testing.T) {
+	codes := config.ExitCodes{{Min: 0, Max: 0}}
+	if err := codes.Set("1,2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if codes.Contains(0) {
+		t.Fatal("Set should replace the default instead of appending")
+	}
+	if !codes.Contains(2) {
+		t.Fatal("Set should apply the new codes")
+	}
+}
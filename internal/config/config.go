@@ -15,10 +15,22 @@ type Config struct {
 	Command string
 	// Args contains arguments for the command.
 	Args []string
+	// Shell is a script run through the platform shell ($SHELL or sh -c,
+	// cmd /C on Windows) instead of Command/Args, so pipelines and && chains
+	// can be written exactly as in the CI YAML.
+	Shell string
+	// Cwd is the working directory for the command.
+	Cwd string
+	// Env are extra KEY=VALUE variables for the command. They win over
+	// EnvFiles, which win over the inherited environment.
+	Env []string
+	// EnvFiles are dotenv-style files loaded in order.
+	EnvFiles []string
 
-	// Forge overrides the automatic forge detection strategy (e.g., "github").
-	// If set, it bypasses the detection logic in DetectClient.
-	Forge string
+	// SinkFlags choose the forge and credentials statuses are reported with.
+	SinkFlags
+	// ForgePolicy says whether all or any of several forges must accept a status.
+	ForgePolicy ForgePolicy
 	// Commit overrides the automatic commit SHA detection.
 	// Useful when running in non-standard CI environments where env vars aren't reliable.
 	Commit string
@@ -28,14 +40,87 @@ type Config struct {
 	URL string
 	// PendingDesc is the description shown while the command is executing.
 	PendingDesc string
+	// MinPendingDelay holds back the running status until the command has
+	// run this long, so fast commands only post their final status.
+	MinPendingDelay time.Duration
 	// SuccessDesc is the description shown when the command exits with code 0.
 	SuccessDesc string
 	// FailureDesc is the description shown when the command fails (non-zero exit code).
 	FailureDesc string
+	// ErrorDesc is the description shown when the exit code is in ErrorCodes.
+	ErrorDesc string
+	// SkipDesc is the description shown when the exit code is in SkipCodes.
+	SkipDesc string
+
+	// SuccessCodes are the exit codes reported as success (default: 0).
+	// Setting them replaces the default, so 0 must be listed explicitly if wanted.
+	SuccessCodes ExitCodes
+	// ErrorCodes are exit codes reported as StateError instead of StateFailure
+	// (e.g. a linter's "crashed" code as opposed to "found issues").
+	ErrorCodes ExitCodes
+	// SkipCodes are exit codes meaning the check did not apply. Forges have no
+	// skipped state, so these post StateSuccess with SkipDesc to keep required
+	// checks green.
+	SkipCodes ExitCodes
+	// NormalizeExitCode makes ci-status exit 0 for success/skipped and 1 for
+	// failure/error instead of forwarding the wrapped command's exit code.
+	// Timeouts still exit 124.
+	NormalizeExitCode bool
 	// Timeout is the maximum duration allowed for the command execution.
 	// If exceeded, the command context is cancelled, the process is terminated,
 	// and a 'StateError' status is reported to the forge.
 	Timeout time.Duration
+	// Retries is how many times a failing command is rerun before the final
+	// status is posted. Each attempt gets its own Timeout.
+	Retries int
+	// RetryOnCodes limits retries to these exit codes. Empty means any code
+	// that would be reported as a failure.
+	RetryOnCodes ExitCodes
+	// RetryDelay is the pause between attempts.
+	RetryDelay time.Duration
+	// Paths are glob patterns ("web/**") of files the command cares about.
+	// When set and none of the files changed since Base, the command is not
+	// run and the status is posted as success with UnchangedDesc.
+	Paths []string
+	// Base is the ref to diff against for Paths. Empty means auto-detect
+	// from pull/merge request environment variables.
+	Base string
+	// UnchangedDesc is the description posted when Paths skipped the command.
+	UnchangedDesc string
+	// CacheKeyFiles are glob patterns (relative to the working directory) of
+	// the command's inputs. When set, a successful run is remembered under a
+	// hash of those files plus the command line, and later runs with the same
+	// hash post success without executing the command.
+	CacheKeyFiles []string
+	// CacheDir is where results are stored. Empty means the user cache dir.
+	CacheDir string
+	// CacheTTL expires cached results older than this. Zero never expires.
+	CacheTTL time.Duration
+	// PTY runs the command under a pseudo-terminal (Linux only) so tools keep
+	// colors and progress bars in CI logs. Stdout and stderr are merged.
+	PTY bool
+	// PTYCols and PTYRows set the pseudo-terminal window size.
+	PTYCols uint16
+	PTYRows uint16
+	// Prefix labels each output line ("[lint] ...") so several background
+	// runs in one job stay readable. "{context}" expands to ContextName.
+	Prefix string
+	// Timestamps adds the elapsed time to each line ("[lint 00:01:23] ...").
+	Timestamps bool
+	// ReportJSON is a file that receives the outcome of the command after it
+	// finishes: final state and description, exit code, timing, retries,
+	// resource usage (CPU time, max RSS, block I/O) and failed status posts.
+	ReportJSON string
+	// PrintUsage prints a /usr/bin/time style summary line to stderr.
+	PrintUsage bool
+	// MemoryLimit and CPULimit cap the command tree through a cgroup v2
+	// child cgroup (Linux with a delegated cgroup only). An OOM kill is
+	// posted as StateError "Killed: out of memory".
+	MemoryLimit ByteSize
+	CPULimit    float64
+	// DryRun prints the status requests to stderr instead of sending them,
+	// and reports even outside CI.
+	DryRun bool
 	// Silent suppresses warnings and diagnostic error lines on stderr
 	// (missing CI, status API failures, timeout/start messages). Exit codes
 	// are unchanged so scripts can still branch on success vs failure.
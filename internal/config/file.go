@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ci-status/internal/gitdir"
+	"ci-status/internal/glob"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFiles are the config file names looked up in the repository root,
+// in order; the first one found is used. The YAML names are shared with
+// pipeline step definitions, which ignore the keys read here.
+var ProjectFiles = []string{".ci-status.toml", ".ci-status.yaml", ".ci-status.yml"}
+
+// UserFiles are the config file names looked up in $XDG_CONFIG_HOME/ci-status.
+var UserFiles = []string{"config.toml", "config.yaml", "config.yml"}
+
+// File is one parsed config file. Keys are flag names ("pending-desc",
+// "timeout"); values are strings, numbers, booleans or lists of them.
+//
+//	[defaults]
+//	forge = "github"
+//	timeout = "10m"
+//
+//	[contexts."test-*"]
+//	success-desc = "Tests passed"
+type File struct {
+	// Path is where the file was read from, used as the setting source.
+	Path     string
+	Defaults map[string]any            `toml:"defaults" yaml:"defaults"`
+	Contexts map[string]map[string]any `toml:"contexts" yaml:"contexts"`
+}
+
+// Setting is one effective value and where it came from.
+type Setting struct {
+	Value any
+	// Source names the file and section, e.g. ".ci-status.toml [defaults]".
+	Source string
+}
+
+// FindFiles returns the existing config files, lowest precedence first:
+// the user file, then the project file in the repository root (the nearest
+// parent of the working directory containing .git, or the working
+// directory itself outside a repository).
+func FindFiles() ([]string, error) {
+	var found []string
+	if dir := userConfigDir(); dir != "" {
+		if path := firstExisting(filepath.Join(dir, "ci-status"), UserFiles); path != "" {
+			found = append(found, path)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("find config file: %w", err)
+	}
+	if path := firstExisting(repoRoot(wd), ProjectFiles); path != "" {
+		found = append(found, path)
+	}
+	return found, nil
+}
+
+// userConfigDir honours $XDG_CONFIG_HOME on every platform (os.UserConfigDir
+// ignores it on macOS and Windows), falling back to the platform default.
+func userConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+// repoRoot returns the work tree of the repository containing dir
+// (honouring GIT_DIR and GIT_WORK_TREE, see gitdir.Open). When that cannot
+// be read, it walks up from dir to the directory containing .git (a
+// directory or, for worktrees and submodules, a file).
+func repoRoot(dir string) string {
+	if repo, err := gitdir.Open(dir); err == nil && repo.WorkTree != "" {
+		return repo.WorkTree
+	}
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+func firstExisting(dir string, names []string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadFile parses a TOML or YAML config file, chosen by extension.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	f := &File{Path: path}
+	switch ext := filepath.Ext(path); ext {
+	case ".toml":
+		err = toml.Unmarshal(data, f)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, f)
+	default:
+		err = fmt.Errorf("unsupported config format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// LoadFiles loads every path in order (see FindFiles).
+func LoadFiles(paths []string) ([]*File, error) {
+	files := make([]*File, 0, len(paths))
+	for _, path := range paths {
+		f, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Resolve merges files (lowest precedence first) into the settings that
+// apply to contextName. Within a file, [defaults] is overridden by matching
+// [contexts] entries: glob patterns first (longer patterns win), then the
+// exact context name. An empty contextName only uses [defaults].
+func Resolve(files []*File, contextName string) map[string]Setting {
+	out := map[string]Setting{}
+	apply := func(values map[string]any, source string) {
+		for k, v := range values {
+			out[k] = Setting{Value: v, Source: source}
+		}
+	}
+	for _, f := range files {
+		apply(f.Defaults, f.Path+" [defaults]")
+		if contextName == "" {
+			continue
+		}
+		for _, pattern := range matchingContexts(f.Contexts, contextName) {
+			apply(f.Contexts[pattern], fmt.Sprintf("%s [contexts.%q]", f.Path, pattern))
+		}
+	}
+	return out
+}
+
+// matchingContexts returns the context keys matching name, in the order
+// they should be applied (least specific first).
+func matchingContexts(contexts map[string]map[string]any, name string) []string {
+	var globs []string
+	for pattern := range contexts {
+		if pattern != name && strings.ContainsAny(pattern, "*?[") && glob.Match(pattern, name) {
+			globs = append(globs, pattern)
+		}
+	}
+	sort.Slice(globs, func(i, j int) bool {
+		if len(globs[i]) != len(globs[j]) {
+			return len(globs[i]) < len(globs[j])
+		}
+		return globs[i] < globs[j]
+	})
+	if _, ok := contexts[name]; ok {
+		globs = append(globs, name)
+	}
+	return globs
+}
+
+// ErrUnknownSetting is returned by Validate for keys no command understands.
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// Validate checks every key in files against known (flag names of all
+// commands), so typos are reported instead of silently ignored.
+func Validate(files []*File, known func(key string) bool) error {
+	for _, f := range files {
+		sections := []map[string]any{f.Defaults}
+		for _, c := range f.Contexts {
+			sections = append(sections, c)
+		}
+		for _, values := range sections {
+			for k := range values {
+				if !known(k) {
+					return fmt.Errorf("%s: %w %q", f.Path, ErrUnknownSetting, k)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// FlagValues renders a setting as the strings to pass to a flag's Set:
+// lists become one value per element, scalars a single value.
+func FlagValues(v any) []string {
+	switch v := v.(type) {
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			out = append(out, fmt.Sprint(e))
+		}
+		return out
+	case []string:
+		return v
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,107 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"ci-status/internal/config"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	tomlPath := filepath.Join(dir, ".ci-status.toml")
+	write(t, tomlPath, "[defaults]\ntimeout = \"5m\"\nretries = 2\n[contexts.\"lint\"]\nsilent = true\n")
+	yamlPath := filepath.Join(dir, ".ci-status.yml")
+	write(t, yamlPath, "steps:\n  - name: ignored\ndefaults:\n  timeout: 5m\n  retries: 2\ncontexts:\n  lint:\n    silent: true\n")
+
+	for _, path := range []string{tomlPath, yamlPath} {
+		f, err := config.LoadFile(path)
+		if err != nil {
+			t.Fatalf("LoadFile(%s): %v", path, err)
+		}
+		got := config.Resolve([]*config.File{f}, "lint")
+		for key, want := range map[string]string{"timeout": "5m", "retries": "2", "silent": "true"} {
+			if v := config.FlagValues(got[key].Value); !slices.Equal(v, []string{want}) {
+				t.Errorf("%s: %s = %q, want %q", filepath.Base(path), key, v, want)
+			}
+		}
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	user := &config.File{Path: "user", Defaults: map[string]any{"forge": "github", "timeout": "1h"}}
+	project := &config.File{
+		Path:     "project",
+		Defaults: map[string]any{"timeout": "10m", "success-desc": "OK"},
+		Contexts: map[string]map[string]any{
+			"test-*":    {"success-desc": "glob", "retries": 1},
+			"test-u*":   {"success-desc": "longer glob"},
+			"test-unit": {"retries": 3},
+		},
+	}
+	got := config.Resolve([]*config.File{user, project}, "test-unit")
+	want := map[string]config.Setting{
+		"forge":        {Value: "github", Source: "user [defaults]"},
+		"timeout":      {Value: "10m", Source: "project [defaults]"},
+		"success-desc": {Value: "longer glob", Source: `project [contexts."test-u*"]`},
+		"retries":      {Value: 3, Source: `project [contexts."test-unit"]`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s = %+v, want %+v", k, got[k], w)
+		}
+	}
+
+	if got := config.Resolve([]*config.File{project}, ""); got["success-desc"].Value != "OK" {
+		t.Errorf("empty context should only use defaults, got %+v", got["success-desc"])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	f := &config.File{Path: "p", Contexts: map[string]map[string]any{"x": {"tiemout": "1m"}}}
+	err := config.Validate([]*config.File{f}, func(k string) bool { return k == "timeout" })
+	if !errors.Is(err, config.ErrUnknownSetting) {
+		t.Fatalf("err = %v, want ErrUnknownSetting", err)
+	}
+}
+
+func TestFindFiles(t *testing.T) {
+	root := t.TempDir()
+	xdg := filepath.Join(root, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	write(t, filepath.Join(xdg, "ci-status", "config.yaml"), "defaults: {}\n")
+	repo := filepath.Join(root, "repo")
+	write(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	write(t, filepath.Join(repo, ".ci-status.toml"), "")
+	write(t, filepath.Join(repo, ".ci-status.yml"), "")
+	sub := filepath.Join(repo, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	got, err := config.FindFiles()
+	if err != nil {
+		t.Fatalf("FindFiles: %v", err)
+	}
+	want := []string{filepath.Join(xdg, "ci-status", "config.yaml"), filepath.Join(repo, ".ci-status.toml")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import "fmt"
+
+// ForgePolicy says when a status counts as posted with several --forge
+// sinks: "all" (the default) needs every sink to accept it, "any" at least
+// one. It implements pflag.Value.
+type ForgePolicy string
+
+const (
+	ForgePolicyAll ForgePolicy = "all"
+	ForgePolicyAny ForgePolicy = "any"
+)
+
+// String implements pflag.Value.
+func (p *ForgePolicy) String() string {
+	if p == nil || *p == "" {
+		return string(ForgePolicyAll)
+	}
+	return string(*p)
+}
+
+// Set implements pflag.Value.
+func (p *ForgePolicy) Set(s string) error {
+	switch ForgePolicy(s) {
+	case ForgePolicyAll, ForgePolicyAny:
+		*p = ForgePolicy(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid forge policy %q (want all|any)", s)
+	}
+}
+
+// Type implements pflag.Value.
+func (p *ForgePolicy) Type() string { return "all|any" }
+
+// RequireAll reports whether every sink must accept a status.
+func (p ForgePolicy) RequireAll() bool {
+	return p != ForgePolicyAny
+}
@@ -0,0 +1,20 @@
+package config_test
+
+import (
+	"testing"
+
+	"ci-status/internal/config"
+)
+
+func TestForgePolicy(t *testing.T) {
+	var p config.ForgePolicy
+	if p.String() != "all" || !p.RequireAll() {
+		t.Fatalf("zero policy = %q, want all", p.String())
+	}
+	if err := p.Set("any"); err != nil || p.RequireAll() {
+		t.Fatalf("Set(any) = %v, RequireAll %t", err, p.RequireAll())
+	}
+	if err := p.Set("some"); err == nil {
+		t.Fatal("Set(some) should fail")
+	}
+}
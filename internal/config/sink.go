@@ -0,0 +1,54 @@
+package config
+
+import (
+	"ci-status/internal/forge"
+	"github.com/spf13/pflag"
+)
+
+// SinkFlags choose the forge statuses are reported to and the credentials
+// used for it. Every command talking to a forge embeds them in its config
+// and registers them with Register, so they read the same everywhere.
+type SinkFlags struct {
+	// Forge overrides the automatic forge detection strategy (e.g., "github").
+	// If set, it bypasses the detection logic in DetectClient. Several values
+	// ("gitea", "github=mirror") report to each of them (see forge.ParseSink).
+	Forge []string
+	// Remote selects the git remote the forge and repository are detected
+	// from instead of origin/upstream.
+	Remote string
+	// Repo ("owner/name") and APIURL bypass parsing the remote, e.g. for a
+	// deploy job in one repository reporting to another.
+	Repo   string
+	APIURL string
+	// TokenFile is read for the forge token before the environment, netrc
+	// and git credential helpers (see package credentials).
+	TokenFile string
+	// GitHubAppID and GitHubAppKeyFile authenticate to GitHub as an app
+	// with installation tokens; GitHubAppInstallationID skips looking the
+	// installation up (see package githubapp).
+	GitHubAppID             string
+	GitHubAppKeyFile        string
+	GitHubAppInstallationID int64
+}
+
+// Register adds the --forge, --remote, --repo, --api-url, --token-file and
+// --github-app-* flags to flags.
+func (s *SinkFlags) Register(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&s.Forge, "forge", nil, "Forge to report to: github, gitea, or NAME=REMOTE with a git remote name or URL (repeatable; default: auto-detect)")
+	flags.StringVar(&s.Remote, "remote", "", "Git remote to detect the forge and repository from (default: origin, then upstream)")
+	flags.StringVar(&s.Repo, "repo", "", "Repository to report to as OWNER/NAME instead of the one parsed from the remote")
+	flags.StringVar(&s.APIURL, "api-url", "", "Forge API base URL (e.g. https://gitea.example.com/api/v1) instead of the one derived from the remote")
+	flags.StringVar(&s.TokenFile, "token-file", "", "Read the forge API token from this file instead of the environment, netrc or git credential helpers")
+	flags.StringVar(&s.GitHubAppID, "github-app-id", "", "Authenticate to GitHub as this GitHub App (app or client id) with installation tokens")
+	flags.StringVar(&s.GitHubAppKeyFile, "github-app-key-file", "", "PEM private key of the --github-app-id app")
+	flags.Int64Var(&s.GitHubAppInstallationID, "github-app-installation-id", 0, "Installation of the --github-app-id app to use (default: the repository's)")
+}
+
+// Sink is the target the flags describe; the Forge values are applied per
+// sink by forge.DetectClients.
+func (s SinkFlags) Sink() forge.Sink {
+	return forge.Sink{
+		Remote: s.Remote, Repo: s.Repo, APIURL: s.APIURL, TokenFile: s.TokenFile,
+		AppID: s.GitHubAppID, AppKeyFile: s.GitHubAppKeyFile, InstallationID: s.GitHubAppInstallationID,
+	}
+}
@@ -0,0 +1,222 @@
+// Package credentials finds the API token for a forge. Sources are tried in
+// order, and the first one holding a token wins:
+//
+//  1. for GitHub, the GitHub App given with --github-app-id (see package
+//     githubapp), whose installation tokens are minted on use;
+//  2. the --token-file given on the command line;
+//  3. the forge's environment variables (see EnvVars);
+//  4. the netrc entry for the API host ($NETRC or ~/.netrc);
+//  5. 'git credential fill' for the remote host, run without prompting.
+//
+// Lookups that find nothing report every source tried, so "no token" errors
+// say where to put one.
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"ci-status/internal/githubapp"
+)
+
+// ErrNotFound is wrapped by *NotFoundError.
+var ErrNotFound = errors.New("no token found")
+
+// ErrEmptyTokenFile is returned when --token-file holds no token.
+var ErrEmptyTokenFile = errors.New("token file is empty")
+
+// EnvVars lists the variables read for each forge, in order. GITHUB_TOKEN
+// stays first for GitHub so existing setups keep their token; Gitea prefers
+// its own variable so a job mirroring to GitHub does not send the GitHub
+// token to Gitea. GITHUB_ENTERPRISE_TOKEN is only read for hosts other than
+// github.com.
+var EnvVars = map[string][]string{
+	"github": {"GITHUB_TOKEN", "GH_TOKEN", "GITHUB_ENTERPRISE_TOKEN"},
+	"gitea":  {"GITEA_TOKEN", "GITHUB_TOKEN"},
+}
+
+// credentialTimeout bounds 'git credential fill', whose helpers may talk to
+// a keychain or a remote service.
+const credentialTimeout = 10 * time.Second
+
+// Request says what a token is needed for.
+type Request struct {
+	// Forge selects the environment variables ("github", "gitea").
+	Forge string
+	// APIHost is the host of the API base URL, matched against netrc
+	// machines ("api.github.com").
+	APIHost string
+	// Host and Protocol identify the remote for git credential helpers
+	// ("github.com", "https").
+	Host     string
+	Protocol string
+	// APIURL, Owner and Repo identify the repository for GitHub App
+	// installation tokens.
+	APIURL string
+	Owner  string
+	Repo   string
+}
+
+// Token is a found token and where it came from.
+type Token struct {
+	Value string
+	// Source names the source for doctor ("GITHUB_TOKEN", "~/.netrc").
+	Source string
+	// Installation is set instead of Value for GitHub App tokens, which
+	// expire and are fetched from it when a request is sent.
+	Installation *githubapp.Installation
+}
+
+// NotFoundError lists the sources tried by a lookup that found nothing.
+type NotFoundError struct {
+	Tried []string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%v (tried %s)", ErrNotFound, strings.Join(e.Tried, ", "))
+}
+
+func (e *NotFoundError) Unwrap() error { return ErrNotFound }
+
+// Resolver looks tokens up. The zero value reads every source but a token
+// file. Results are cached per Request, so detection can ask for the same
+// token repeatedly without rerunning credential helpers.
+type Resolver struct {
+	// TokenFile is read first when set; a missing or empty file is an
+	// error rather than a fallthrough, since it was asked for explicitly.
+	TokenFile string
+	// GitHubApp, when set, authenticates GitHub requests ahead of every
+	// other source. Other forges still use the sources above.
+	GitHubApp *githubapp.App
+
+	mu    sync.Mutex
+	cache map[Request]result
+}
+
+type result struct {
+	token Token
+	err   error
+}
+
+// Lookup returns the first token found for req. Errors are
+// *NotFoundError or token file errors.
+func (r *Resolver) Lookup(req Request) (Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res, ok := r.cache[req]; ok {
+		return res.token, res.err
+	}
+	token, err := r.lookup(req)
+	if r.cache == nil {
+		r.cache = map[Request]result{}
+	}
+	r.cache[req] = result{token, err}
+	return token, err
+}
+
+func (r *Resolver) lookup(req Request) (Token, error) {
+	var tried []string
+
+	if r.GitHubApp != nil && req.Forge == "github" {
+		return Token{
+			Source:       "GitHub App " + r.GitHubApp.ID,
+			Installation: r.GitHubApp.Installation(req.APIURL, req.Owner, req.Repo),
+		}, nil
+	}
+
+	if r.TokenFile != "" {
+		value, err := readTokenFile(r.TokenFile)
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Value: value, Source: "--token-file " + r.TokenFile}, nil
+	}
+
+	for _, name := range envVarsFor(req) {
+		if value := sanitize(os.Getenv(name)); value != "" {
+			return Token{Value: value, Source: name}, nil
+		}
+		tried = append(tried, "$"+name)
+	}
+
+	if req.APIHost != "" {
+		path := netrcPath()
+		if path != "" {
+			if value := netrcPassword(path, req.APIHost); value != "" {
+				return Token{Value: value, Source: path}, nil
+			}
+			tried = append(tried, fmt.Sprintf("%s (machine %s)", path, req.APIHost))
+		}
+	}
+
+	if req.Host != "" {
+		if value := gitCredential(req); value != "" {
+			return Token{Value: value, Source: "git credential fill"}, nil
+		}
+		tried = append(tried, fmt.Sprintf("git credential fill (host %s)", req.Host))
+	}
+
+	return Token{}, &NotFoundError{Tried: tried}
+}
+
+// envVarsFor is EnvVars[req.Forge], without the enterprise variable for
+// github.com.
+func envVarsFor(req Request) []string {
+	var names []string
+	for _, name := range EnvVars[req.Forge] {
+		if name == "GITHUB_ENTERPRISE_TOKEN" && (req.APIHost == "" || req.APIHost == "api.github.com") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// sanitize removes CR and LF anywhere in a token (not just at the ends) so
+// it cannot inject headers; trailing newlines from files and helpers go too.
+func sanitize(token string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(token)
+}
+
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	value := sanitize(string(data))
+	if strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("%w: %s", ErrEmptyTokenFile, path)
+	}
+	return value, nil
+}
+
+// gitCredential asks the configured git credential helpers for the remote
+// host's password. Prompts are disabled so CI never hangs on a terminal or
+// a credential manager dialog; any failure means "no token".
+func gitCredential(req Request) string {
+	protocol := req.Protocol
+	if protocol == "" {
+		protocol = "https"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), credentialTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", protocol, sanitize(req.Host)))
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if value, ok := strings.CutPrefix(line, "password="); ok {
+			return sanitize(value)
+		}
+	}
+	return ""
+}
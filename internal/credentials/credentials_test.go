@@ -0,0 +1,116 @@
+package credentials_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"ci-status/internal/credentials"
+	"ci-status/internal/credentials/credentialstest"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var github = credentials.Request{Forge: "github", APIHost: "api.github.com", Host: "github.com", Protocol: "https"}
+
+func TestLookupOrder(t *testing.T) {
+	netrc := credentialstest.Isolate(t)
+	writeFile(t, netrc, "machine api.github.com login x password from-netrc\n")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenFile, "from-file\r\n")
+
+	t.Setenv("GH_TOKEN", "from-gh")
+	lookup := func(r *credentials.Resolver, req credentials.Request) credentials.Token {
+		t.Helper()
+		token, err := r.Lookup(req)
+		if err != nil {
+			t.Fatalf("Lookup(%+v): %v", req, err)
+		}
+		return token
+	}
+
+	if got := lookup(&credentials.Resolver{TokenFile: tokenFile}, github); got.Value != "from-file" || got.Source != "--token-file "+tokenFile {
+		t.Errorf("token file: %+v", got)
+	}
+	if got := lookup(&credentials.Resolver{}, github); got.Value != "from-gh" || got.Source != "GH_TOKEN" {
+		t.Errorf("env: %+v", got)
+	}
+	t.Setenv("GITHUB_TOKEN", "from-github")
+	if got := lookup(&credentials.Resolver{}, github); got.Source != "GITHUB_TOKEN" {
+		t.Errorf("GITHUB_TOKEN should come first: %+v", got)
+	}
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	if got := lookup(&credentials.Resolver{}, github); got.Value != "from-netrc" || got.Source != netrc {
+		t.Errorf("netrc: %+v", got)
+	}
+}
+
+func TestLookupEnvPerForge(t *testing.T) {
+	credentialstest.Isolate(t)
+	t.Setenv("GITHUB_TOKEN", "github")
+	t.Setenv("GITEA_TOKEN", "gitea")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "ghes")
+
+	gitea := credentials.Request{Forge: "gitea", APIHost: "gitea.example.com"}
+	if got, err := new(credentials.Resolver).Lookup(gitea); err != nil || got.Source != "GITEA_TOKEN" {
+		t.Errorf("gitea: %+v, %v", got, err)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	if _, err := new(credentials.Resolver).Lookup(credentials.Request{Forge: "github", APIHost: "api.github.com"}); err == nil {
+		t.Error("GITHUB_ENTERPRISE_TOKEN must not be sent to github.com")
+	}
+	ghes := credentials.Request{Forge: "github", APIHost: "ghe.example.com"}
+	if got, err := new(credentials.Resolver).Lookup(ghes); err != nil || got.Value != "ghes" {
+		t.Errorf("enterprise: %+v, %v", got, err)
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	netrc := credentialstest.Isolate(t)
+	writeFile(t, netrc, "machine gitea.example.com password other-host\ndefault password everywhere\n")
+
+	_, err := new(credentials.Resolver).Lookup(github)
+	var notFound *credentials.NotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, credentials.ErrNotFound) {
+		t.Fatalf("err = %v, want *NotFoundError", err)
+	}
+	want := []string{"$GITHUB_TOKEN", "$GH_TOKEN", netrc + " (machine api.github.com)", "git credential fill (host github.com)"}
+	if !slices.Equal(notFound.Tried, want) {
+		t.Errorf("Tried = %q, want %q", notFound.Tried, want)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenFile, "\n")
+	t.Setenv("GITHUB_TOKEN", "ignored")
+	if _, err := (&credentials.Resolver{TokenFile: tokenFile}).Lookup(github); !errors.Is(err, credentials.ErrEmptyTokenFile) {
+		t.Errorf("empty token file: %v, want ErrEmptyTokenFile", err)
+	}
+	if _, err := (&credentials.Resolver{TokenFile: tokenFile + ".missing"}).Lookup(github); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing token file: %v, want ErrNotExist", err)
+	}
+}
+
+func TestLookupGitCredential(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	credentialstest.Isolate(t)
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", `!f() { test "$1" = get && echo username=x && echo password=from-helper; }; f`)
+
+	token, err := new(credentials.Resolver).Lookup(credentials.Request{Forge: "gitea", Host: "gitea.example.com"})
+	if err != nil || token.Value != "from-helper" || token.Source != "git credential fill" {
+		t.Fatalf("Lookup = %+v, %v", token, err)
+	}
+}
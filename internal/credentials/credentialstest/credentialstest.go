@@ -0,0 +1,30 @@
+// Package credentialstest hides the user's forge tokens from tests, so
+// detection sees only the credentials a test sets up itself.
+package credentialstest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ci-status/internal/credentials"
+)
+
+// Isolate hides every token source for the rest of t: the variables in
+// credentials.EnvVars, the user's netrc and their git credential helpers.
+// It returns the netrc path that is read instead; the file does not exist
+// until the test writes it.
+func Isolate(t testing.TB) string {
+	t.Helper()
+	for _, names := range credentials.EnvVars {
+		for _, name := range names {
+			t.Setenv(name, "")
+		}
+	}
+	dir := t.TempDir()
+	netrc := filepath.Join(dir, "netrc")
+	t.Setenv("NETRC", netrc)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	return netrc
+}
@@ -0,0 +1,77 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcPath is $NETRC, or ~/.netrc (~/_netrc on Windows when only that
+// exists, as curl does). It returns "" when there is no home directory.
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".netrc")
+	if runtime.GOOS == "windows" {
+		if _, err := os.Stat(path); err != nil {
+			return filepath.Join(home, "_netrc")
+		}
+	}
+	return path
+}
+
+// netrcPassword returns the password of the "machine <host>" entry in the
+// netrc file at path, or "" when there is none. The host is matched with
+// and without its port. "default" entries are ignored: they would send one
+// password to every forge.
+func netrcPassword(path, host string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	hostname := host
+	if i := strings.LastIndexByte(host, ':'); i > 0 && !strings.Contains(host[i:], "]") {
+		hostname = host[:i]
+	}
+
+	fields := strings.Fields(string(data))
+	var machine, password string
+	flush := func() string {
+		if password != "" && (strings.EqualFold(machine, host) || strings.EqualFold(machine, hostname)) {
+			return password
+		}
+		return ""
+	}
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine", "default":
+			if p := flush(); p != "" {
+				return p
+			}
+			machine, password = "", ""
+			if fields[i] == "machine" && i+1 < len(fields) {
+				i++
+				machine = fields[i]
+			}
+		case "password":
+			if i+1 < len(fields) {
+				i++
+				password = fields[i]
+			}
+		case "login", "account":
+			i++
+		case "macdef":
+			// A macro runs to the next blank line; skip to the next entry.
+			for i+1 < len(fields) && fields[i+1] != "machine" && fields[i+1] != "default" {
+				i++
+			}
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,39 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNetrcPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	content := `machine ghe.example.com login u password ghe
+macdef init
+cd /pub
+bin
+
+machine gitea.example.com:3000
+	login u
+	password gitea
+default login anonymous password everyone
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for host, want := range map[string]string{
+		"ghe.example.com":        "ghe",
+		"ghe.example.com:8443":   "ghe",
+		"gitea.example.com:3000": "gitea",
+		"gitea.example.com":      "",
+		"api.github.com":         "",
+	} {
+		if got := netrcPassword(path, host); got != want {
+			t.Errorf("netrcPassword(%q) = %q, want %q", host, got, want)
+		}
+	}
+	if got := netrcPassword(path+".missing", "ghe.example.com"); got != "" {
+		t.Errorf("missing file: %q", got)
+	}
+}
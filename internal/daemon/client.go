@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"ci-status/internal/forge"
+)
+
+// ErrRejected is returned by Client.SetStatus when the daemon refused an update.
+var ErrRejected = errors.New("daemon rejected status")
+
+// Client is a forge.ForgeClient that sends statuses to a daemon. It is safe
+// for concurrent use; requests on the connection are serialized.
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// Dial connects to the daemon listening on the Unix socket at path.
+func Dial(ctx context.Context, path string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("connect to daemon: %w", err)
+	}
+	return &Client{conn: conn, scanner: bufio.NewScanner(conn)}, nil
+}
+
+// SetStatus queues opts in the daemon. It returns once the daemon accepted
+// the update, not when it was posted to the forge. An empty Commit uses the
+// daemon's commit.
+func (c *Client) SetStatus(ctx context.Context, opts forge.StatusOpts) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetDeadline(deadline)
+	} else {
+		_ = c.conn.SetDeadline(time.Time{})
+	}
+	req := Request{
+		Context:     opts.Context,
+		State:       string(opts.State),
+		Description: opts.Description,
+		URL:         opts.TargetURL,
+		Commit:      opts.Commit,
+	}
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return fmt.Errorf("send to daemon: %w", err)
+	}
+	if !c.scanner.Scan() {
+		err := c.scanner.Err()
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		return fmt.Errorf("read daemon response: %w", err)
+	}
+	var resp Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return fmt.Errorf("read daemon response: %w", err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("%w: %s", ErrRejected, resp.Error)
+	}
+	return nil
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
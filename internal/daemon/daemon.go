@@ -0,0 +1,398 @@
+// Package daemon implements 'ci-status serve': a background process that
+// holds one forge client and posts statuses sent to it over a Unix socket.
+//
+// The protocol is one JSON object per line in each direction. A request is
+// a status ({"context": "lint", "state": "success", "description": "..."}),
+// answered with {"ok": true} once it is queued, or {"ok": false, "error": ...}.
+// Queued updates to the same commit and context are coalesced (only the
+// latest is posted); dropping repeats of a posted status is left to Client
+// (serve wraps it in a forge.DedupClient). Failed posts are re-queued and retried with backoff, waiting as
+// long as the forge asks when it is rate limited. Everything still queued is
+// posted on shutdown, retrying until the shutdown deadline.
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"ci-status/internal/forge"
+)
+
+// DefaultInterval is how long updates are coalesced before being posted.
+const DefaultInterval = 500 * time.Millisecond
+
+// shutdownTimeout bounds the final flush once the daemon is stopped.
+const shutdownTimeout = 30 * time.Second
+
+// maxInFlight caps concurrent posts of one flush.
+const maxInFlight = 8
+
+// retryBackoff is the wait before the first retry of a failed post; it
+// doubles per attempt up to maxRetryBackoff. Rate-limit rejections wait
+// for their RetryAfter instead, when the forge gave one.
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// Request is one status update on the wire. Commit may be empty to use the
+// daemon's commit.
+type Request struct {
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Commit      string `json:"commit,omitempty"`
+}
+
+// Response acknowledges one Request.
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrNoCommit       = errors.New("commit not available")
+	ErrNotSocket      = errors.New("exists and is not a socket")
+)
+
+// Server coalesces and posts status updates. Client may be nil (no forge
+// detected, or not in CI): updates are then acknowledged and dropped, so
+// scripts behave the same as set outside CI.
+type Server struct {
+	Client forge.ForgeClient
+	// Commit is used for requests that do not name one.
+	Commit string
+	// Interval is the coalescing window (DefaultInterval when zero).
+	Interval time.Duration
+	// OnError is called for every failed post. The update stays queued and
+	// is retried unless a newer one for its context replaced it.
+	OnError func(opts forge.StatusOpts, err error)
+
+	mu      sync.Mutex
+	pending map[statusKey]forge.StatusOpts
+	// order keeps pending keys in arrival order so contexts are posted
+	// in the order they were first updated.
+	order []statusKey
+	wake  chan struct{}
+	// retries holds the backoff of keys whose last post failed.
+	retries map[statusKey]retry
+	// limited holds every post back until the forge's rate limit resets.
+	limited time.Time
+}
+
+type statusKey struct{ commit, context string }
+
+// retry is the backoff state of one key: attempts failed posts so far, and
+// no new post before due.
+type retry struct {
+	attempts int
+	due      time.Time
+}
+
+// Submit validates opts and queues them, replacing any queued update for
+// the same commit and context.
+func (s *Server) Submit(opts forge.StatusOpts) error {
+	if opts.Context == "" {
+		return fmt.Errorf("%w: context missing", ErrInvalidRequest)
+	}
+	switch opts.State {
+	case forge.StatePending, forge.StateRunning, forge.StateSuccess, forge.StateFailure, forge.StateError:
+	default:
+		return fmt.Errorf("%w: invalid state %q", ErrInvalidRequest, opts.State)
+	}
+	if opts.Commit == "" {
+		opts.Commit = s.Commit
+	}
+	if s.Client == nil {
+		return nil
+	}
+	if opts.Commit == "" {
+		return ErrNoCommit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	key := statusKey{opts.Commit, opts.Context}
+	if _, queued := s.pending[key]; !queued {
+		s.order = append(s.order, key)
+	}
+	s.pending[key] = opts
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// init allocates the queues; s.mu must be held.
+func (s *Server) init() {
+	if s.pending == nil {
+		s.pending = map[statusKey]forge.StatusOpts{}
+		s.retries = map[statusKey]retry{}
+		s.wake = make(chan struct{}, 1)
+	}
+}
+
+// Flush posts every queued update that is due and waits for the posts to
+// finish. Failed updates are re-queued with backoff; Flush returns when the
+// earliest queued update is due again, or the zero time when none is left.
+func (s *Server) Flush(ctx context.Context) time.Time {
+	s.mu.Lock()
+	s.init()
+	now := time.Now()
+	if now.Before(s.limited) {
+		s.mu.Unlock()
+		return s.limited
+	}
+	batch := make([]forge.StatusOpts, 0, len(s.order))
+	var waiting []statusKey
+	for _, key := range s.order {
+		if now.Before(s.retries[key].due) {
+			waiting = append(waiting, key)
+			continue
+		}
+		batch = append(batch, s.pending[key])
+		delete(s.pending, key)
+	}
+	s.order = waiting
+	s.mu.Unlock()
+
+	sem := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	for _, opts := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := s.Client.SetStatus(ctx, opts)
+			if err != nil && s.OnError != nil {
+				s.OnError(opts, err)
+			}
+			s.done(opts, err)
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next time.Time
+	for _, key := range s.order {
+		due := s.retries[key].due
+		if due.Before(s.limited) {
+			due = s.limited
+		}
+		if next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
+
+// done records the outcome of posting opts: on failure the backoff,
+// re-queueing opts unless a newer update for the same key arrived meanwhile.
+func (s *Server) done(opts forge.StatusOpts, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := statusKey{opts.Commit, opts.Context}
+	if err == nil {
+		delete(s.retries, key)
+		return
+	}
+
+	now := time.Now()
+	r := s.retries[key]
+	wait := min(retryBackoff<<r.attempts, maxRetryBackoff)
+	var rl *forge.RateLimitError
+	if errors.As(err, &rl) {
+		if rl.RetryAfter > 0 {
+			wait = rl.RetryAfter
+		}
+		// The limit applies to the whole token, not just this context.
+		if until := now.Add(wait); until.After(s.limited) {
+			s.limited = until
+		}
+	}
+	r.attempts++
+	r.due = now.Add(wait)
+	s.retries[key] = r
+	if _, queued := s.pending[key]; !queued {
+		s.pending[key] = opts
+		s.order = append(s.order, key)
+	}
+}
+
+// drain flushes until nothing is queued or ctx is done. Updates that still
+// failed at the deadline are dropped; OnError has reported them.
+func (s *Server) drain(ctx context.Context) {
+	for {
+		next := s.Flush(ctx)
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Listen creates the Unix socket at path. A leftover socket file nobody
+// is listening on (from a killed daemon) is replaced; a live one, or any
+// other file at path, is an error.
+func Listen(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen on %s: %w", path, ErrNotSocket)
+		}
+		if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("listen on %s: another daemon is running", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("listen on %s: %w", path, err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l until ctx is cancelled, then closes l,
+// drops open connections and posts everything still queued. The listener's
+// socket file is removed by l.Close.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	s.mu.Lock()
+	s.init()
+	s.mu.Unlock()
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	// Cancelled on accept errors too, so shutdown runs on every exit path.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var conns sync.Map
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+		conns.Range(func(c, _ any) bool {
+			_ = c.(net.Conn).Close()
+			return true
+		})
+		close(done)
+	}()
+
+	// Flusher: wait for an update, let more arrive for one interval, post.
+	// Failed posts are flushed again once their backoff is over.
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		var next time.Time
+		for {
+			var retryC <-chan time.Time
+			var timer *time.Timer
+			if !next.IsZero() {
+				timer = time.NewTimer(time.Until(next))
+				retryC = timer.C
+			}
+			select {
+			case <-done:
+				return
+			case <-retryC:
+			case <-s.wake:
+				select {
+				case <-done:
+					return
+				case <-time.After(interval):
+				}
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			if s.Client != nil {
+				// Posts already started finish even when stopped mid-flush.
+				next = s.Flush(context.WithoutCancel(ctx))
+			}
+		}
+	}()
+
+	var acceptErr error
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				acceptErr = fmt.Errorf("accept: %w", err)
+				cancel()
+			}
+			break
+		}
+		conns.Store(conn, struct{}{})
+		if ctx.Err() != nil {
+			// Stopped between Accept and Store: the closer may have missed it.
+			_ = conn.Close()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conns.Delete(conn)
+			s.handle(conn)
+		}()
+	}
+
+	<-done
+	wg.Wait()
+	<-flushed
+	if s.Client != nil {
+		final, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+		s.drain(final)
+	}
+	return acceptErr
+}
+
+// handle answers the requests of one connection until it is closed.
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		resp := Response{OK: true}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Response{Error: fmt.Sprintf("%v: %v", ErrInvalidRequest, err)}
+		} else if err := s.Submit(forge.StatusOpts{
+			Commit:      req.Commit,
+			Context:     req.Context,
+			State:       forge.State(req.State),
+			Description: req.Description,
+			TargetURL:   req.URL,
+		}); err != nil {
+			resp = Response{Error: err.Error()}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
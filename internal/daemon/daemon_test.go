@@ -0,0 +1,258 @@
+package daemon_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ci-status/internal/daemon"
+	"ci-status/internal/forge"
+)
+
+type recordingClient struct {
+	mu    sync.Mutex
+	posts []forge.StatusOpts
+}
+
+func (c *recordingClient) SetStatus(_ context.Context, opts forge.StatusOpts) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.posts = append(c.posts, opts)
+	return nil
+}
+
+func (c *recordingClient) snapshot() []forge.StatusOpts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]forge.StatusOpts(nil), c.posts...)
+}
+
+// socketPath returns a short socket path: t.TempDir() can exceed the
+// ~104-byte sun_path limit on macOS.
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "cis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "d.sock")
+}
+
+// startServer runs srv on a fresh socket; the returned stop shuts it down
+// and waits for the final flush.
+func startServer(t *testing.T, srv *daemon.Server) (path string, stop func()) {
+	path = socketPath(t)
+	l, err := daemon.Listen(path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, l) }()
+	return path, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}
+}
+
+func TestServerCoalescesAndFlushesOnShutdown(t *testing.T) {
+	client := &recordingClient{}
+	path, stop := startServer(t, &daemon.Server{Client: client, Commit: "abc", Interval: time.Hour})
+
+	c, err := daemon.Dial(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	for _, opts := range []forge.StatusOpts{
+		{Context: "lint", State: forge.StateRunning, Description: "1/3"},
+		{Context: "test", State: forge.StatePending},
+		{Context: "lint", State: forge.StateRunning, Description: "2/3"},
+		{Context: "lint", State: forge.StateSuccess, Description: "done"},
+		{Context: "test", State: forge.StateFailure, Commit: "def"},
+	} {
+		if err := c.SetStatus(t.Context(), opts); err != nil {
+			t.Fatalf("SetStatus(%+v): %v", opts, err)
+		}
+	}
+	_ = c.Close()
+	if got := client.snapshot(); len(got) != 0 {
+		t.Fatalf("posted before the interval elapsed: %+v", got)
+	}
+	stop()
+
+	want := []forge.StatusOpts{
+		{Commit: "abc", Context: "lint", State: forge.StateSuccess, Description: "done"},
+		{Commit: "abc", Context: "test", State: forge.StatePending},
+		{Commit: "def", Context: "test", State: forge.StateFailure},
+	}
+	got := client.snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("posts = %+v, want %+v", got, want)
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == w
+		}
+		if !found {
+			t.Errorf("missing post %+v in %+v", w, got)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file should be removed on shutdown: %v", err)
+	}
+}
+
+func TestServerRepeatsDroppedByDedupClient(t *testing.T) {
+	client := &recordingClient{}
+	srv := &daemon.Server{Client: forge.NewDedupClient(client), Commit: "abc"}
+	opts := forge.StatusOpts{Context: "lint", State: forge.StateRunning, Description: "Running..."}
+
+	for range 2 {
+		if err := srv.Submit(opts); err != nil {
+			t.Fatal(err)
+		}
+		srv.Flush(t.Context())
+	}
+	if got := client.snapshot(); len(got) != 1 {
+		t.Fatalf("posts = %+v, want the repeat dropped", got)
+	}
+}
+
+// flakyClient fails the first fails posts with err, then records posts.
+type flakyClient struct {
+	recordingClient
+	fails int
+	err   error
+}
+
+func (c *flakyClient) SetStatus(ctx context.Context, opts forge.StatusOpts) error {
+	c.mu.Lock()
+	if c.fails > 0 {
+		c.fails--
+		c.mu.Unlock()
+		return c.err
+	}
+	c.mu.Unlock()
+	return c.recordingClient.SetStatus(ctx, opts)
+}
+
+func TestServerRetriesFailedPosts(t *testing.T) {
+	client := &flakyClient{fails: 1, err: &forge.RateLimitError{RetryAfter: 50 * time.Millisecond, Err: errors.New("429")}}
+	var failed []forge.StatusOpts
+	srv := &daemon.Server{Client: client, Commit: "abc", OnError: func(opts forge.StatusOpts, _ error) {
+		failed = append(failed, opts)
+	}}
+	lint := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateRunning}
+	if err := srv.Submit(lint); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	next := srv.Flush(t.Context())
+	if len(failed) != 1 || next.Sub(start) < 50*time.Millisecond {
+		t.Fatalf("failed = %+v, next retry in %s; want the rate limit honoured", failed, next.Sub(start))
+	}
+	// Still rate limited: nothing is posted early.
+	if again := srv.Flush(t.Context()); !again.Equal(next) || len(client.snapshot()) != 0 {
+		t.Fatalf("flushed during the rate limit: next %v, posts %+v", again, client.snapshot())
+	}
+
+	// A newer update replaces the one being retried.
+	done := lint
+	done.State = forge.StateSuccess
+	if err := srv.Submit(done); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(next))
+	if next := srv.Flush(t.Context()); !next.IsZero() {
+		t.Fatalf("next = %v after a successful retry, want nothing queued", next)
+	}
+	if got := client.snapshot(); len(got) != 1 || got[0] != done {
+		t.Fatalf("posts = %+v, want only %+v", got, done)
+	}
+}
+
+func TestServerRetriesOnShutdown(t *testing.T) {
+	client := &flakyClient{fails: 1, err: errors.New("502 Bad Gateway")}
+	srv := &daemon.Server{Client: client, Commit: "abc", Interval: time.Hour}
+	path, stop := startServer(t, srv)
+
+	c, err := daemon.Dial(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if err := c.SetStatus(t.Context(), forge.StatusOpts{Context: "lint", State: forge.StateSuccess}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	_ = c.Close()
+	stop()
+
+	if got := client.snapshot(); len(got) != 1 {
+		t.Fatalf("posts = %+v, want the final status retried", got)
+	}
+}
+
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	path, stop := startServer(t, &daemon.Server{Client: &recordingClient{}, Commit: "abc"})
+	defer stop()
+
+	c, err := daemon.Dial(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	for _, opts := range []forge.StatusOpts{
+		{Context: "lint", State: "bogus"},
+		{State: forge.StateSuccess},
+	} {
+		if err := c.SetStatus(t.Context(), opts); !errors.Is(err, daemon.ErrRejected) {
+			t.Errorf("SetStatus(%+v) = %v, want ErrRejected", opts, err)
+		}
+	}
+	// The connection stays usable after a rejection.
+	if err := c.SetStatus(t.Context(), forge.StatusOpts{Context: "lint", State: forge.StateSuccess}); err != nil {
+		t.Fatalf("SetStatus after rejection: %v", err)
+	}
+}
+
+func TestListenRefusesLiveSocketAndReplacesStale(t *testing.T) {
+	path, stop := startServer(t, &daemon.Server{})
+	if _, err := daemon.Listen(path); err == nil {
+		t.Fatal("Listen should refuse a socket another daemon serves")
+	}
+	stop()
+
+	// A socket left behind by a killed daemon.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+	l, err := daemon.Listen(path)
+	if err != nil {
+		t.Fatalf("Listen over stale socket: %v", err)
+	}
+	_ = l.Close()
+}
+
+func TestListenKeepsOtherFiles(t *testing.T) {
+	path := socketPath(t)
+	if err := os.WriteFile(path, []byte("module x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := daemon.Listen(path); !errors.Is(err, daemon.ErrNotSocket) {
+		t.Fatalf("Listen over a regular file = %v, want ErrNotSocket", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "module x\n" {
+		t.Fatalf("file was modified: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,27 @@
+package executor
+
+import "errors"
+
+// ErrOutOfMemory is returned by Run when the kernel OOM killer stopped the
+// command because it exceeded Limits.MemoryBytes. The exit code is still the
+// one the process died with (usually 137).
+var ErrOutOfMemory = errors.New("killed: out of memory")
+
+// ErrCgroupUnavailable is passed to Executor.OnLimitsUnavailable when Limits
+// are set but no usable cgroup v2 hierarchy (with the memory/cpu controllers
+// delegated to us) exists.
+var ErrCgroupUnavailable = errors.New("cgroup v2 resource limits unavailable")
+
+// Limits caps the resources of the whole command tree. The zero value means
+// no limits and no cgroup is created.
+type Limits struct {
+	// MemoryBytes is written to memory.max; swap is disabled for the cgroup
+	// so the limit is not silently extended.
+	MemoryBytes int64
+	// CPUs is the number of CPUs worth of time per period (cpu.max), e.g. 1.5.
+	CPUs float64
+}
+
+func (l Limits) active() bool {
+	return l.MemoryBytes > 0 || l.CPUs > 0
+}
@@ -0,0 +1,330 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// cgroupRoot is where the unified (v2) hierarchy is mounted. Hybrid hosts
+// that only mount v2 under /sys/fs/cgroup/unified have no controllers there,
+// so they are reported as unavailable rather than half-supported.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// procSelfCgroup lists the cgroups of this process.
+var procSelfCgroup = "/proc/self/cgroup"
+
+// supervisorLeaf is the cgroup ci-status moves itself into when its own
+// cgroup must give up its processes to delegate controllers.
+const supervisorLeaf = "ci-status-supervisor"
+
+// cpuPeriod is the cpu.max period in microseconds (the kernel default).
+const cpuPeriod = 100000
+
+// cgroupSeq keeps cgroup names unique when one process runs several commands.
+var cgroupSeq atomic.Int64
+
+// delegation records what enableControllers changed in our own cgroup, so
+// the removal of the last command cgroup can undo it and leave the runner's
+// hierarchy as it found it.
+var delegation struct {
+	sync.Mutex
+	// users counts command cgroups alive (see acquireControllers).
+	users int
+	// parent is the cgroup command cgroups are created in, resolved once
+	// while users > 0: moving into leaf changes /proc/self/cgroup.
+	parent string
+	// added lists the controllers ci-status enabled in parent.
+	added []string
+	// leaf is the cgroup ci-status moved itself into, "" when not moved.
+	leaf string
+}
+
+// cgroup is a leaf cgroup created for one command.
+type cgroup struct {
+	dir string
+	fd  *os.File
+	// held is set once acquireControllers succeeded.
+	held bool
+}
+
+// newCgroup creates a child of our own cgroup with the given limits. The
+// parent must be delegated to us (writable, memory/cpu in cgroup.controllers),
+// e.g. by systemd-run --user -p Delegate=yes or a container runtime.
+func newCgroup(l Limits) (*cgroup, error) {
+	var want []string
+	if l.MemoryBytes > 0 {
+		want = append(want, "memory")
+	}
+	if l.CPUs > 0 {
+		want = append(want, "cpu")
+	}
+	parent, err := acquireControllers(want)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(parent, fmt.Sprintf("ci-status-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		releaseControllers()
+		return nil, fmt.Errorf("%w: %v", ErrCgroupUnavailable, err)
+	}
+	cg := &cgroup{dir: dir, held: true}
+	if err := cg.apply(l); err != nil {
+		cg.remove()
+		return nil, err
+	}
+	if cg.fd, err = os.Open(dir); err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("%w: %v", ErrCgroupUnavailable, err)
+	}
+	return cg, nil
+}
+
+// ownCgroup returns the directory of our cgroup v2, or its parent while
+// we are in the supervisor leaf (left there when restoreDelegation could
+// not move us back).
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile(procSelfCgroup)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrCgroupUnavailable, err)
+	}
+	rel, ok := unifiedPath(string(data))
+	if !ok {
+		return "", fmt.Errorf("%w: not on a cgroup v2 hierarchy", ErrCgroupUnavailable)
+	}
+	dir := filepath.Join(cgroupRoot, rel)
+	if filepath.Base(dir) == supervisorLeaf {
+		dir = filepath.Dir(dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("%w: %s is not a cgroup v2 mount", ErrCgroupUnavailable, cgroupRoot)
+	}
+	return dir, nil
+}
+
+// unifiedPath returns the v2 path from /proc/self/cgroup ("0::/path").
+func unifiedPath(procCgroup string) (string, bool) {
+	for _, line := range strings.Split(procCgroup, "\n") {
+		if rest, ok := strings.CutPrefix(line, "0::"); ok {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+// acquireControllers enables the wanted controllers (see enableControllers)
+// for one more command cgroup and returns the cgroup to create it in;
+// releaseControllers undoes it after the last.
+func acquireControllers(want []string) (string, error) {
+	delegation.Lock()
+	defer delegation.Unlock()
+	if delegation.users == 0 {
+		parent, err := ownCgroup()
+		if err != nil {
+			return "", err
+		}
+		delegation.parent = parent
+	}
+	if err := enableControllers(delegation.parent, want); err != nil {
+		if delegation.users == 0 {
+			restoreDelegation()
+		}
+		return "", err
+	}
+	delegation.users++
+	return delegation.parent, nil
+}
+
+// releaseControllers drops one command cgroup; the last one restores our
+// cgroup (see restoreDelegation).
+func releaseControllers() {
+	delegation.Lock()
+	defer delegation.Unlock()
+	delegation.users--
+	if delegation.users == 0 {
+		restoreDelegation()
+	}
+}
+
+// enableControllers makes the wanted controllers available to children of
+// parent, recording the ones it added in delegation. Cgroup v2 forbids
+// enabling controllers for a cgroup that still has processes ("no internal
+// processes"), so on EBUSY ci-status moves itself into a leaf sibling first,
+// as systemd does for delegated scopes. restoreDelegation moves it back.
+func enableControllers(parent string, want []string) error {
+	control := filepath.Join(parent, "cgroup.subtree_control")
+	current, err := os.ReadFile(control)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCgroupUnavailable, err)
+	}
+	enabled := strings.Fields(string(current))
+	var add []string
+	for _, c := range want {
+		if !contains(enabled, c) {
+			add = append(add, c)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+	line := []byte("+" + strings.Join(add, " +"))
+
+	err = os.WriteFile(control, line, 0)
+	if errors.Is(err, syscall.EBUSY) && delegation.leaf == "" {
+		leaf := filepath.Join(parent, supervisorLeaf)
+		if mkErr := os.Mkdir(leaf, 0o755); mkErr != nil && !errors.Is(mkErr, os.ErrExist) {
+			return fmt.Errorf("%w: %v", ErrCgroupUnavailable, mkErr)
+		}
+		delegation.leaf = leaf
+		if mvErr := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); mvErr != nil {
+			return fmt.Errorf("%w: move ci-status out of %s: %v", ErrCgroupUnavailable, parent, mvErr)
+		}
+		err = os.WriteFile(control, line, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: enable %s in %s: %v (is the cgroup delegated?)", ErrCgroupUnavailable, strings.Join(want, ","), parent, err)
+	}
+	delegation.added = append(delegation.added, add...)
+	return nil
+}
+
+// restoreDelegation disables the controllers ci-status enabled, moves it
+// back from its leaf cgroup and removes the leaf. The controllers go first:
+// the parent may not hold processes while they are enabled. Nothing is
+// undone while other child cgroups (e.g. another ci-status) may still rely
+// on the controllers. Best-effort; called with delegation locked.
+func restoreDelegation() {
+	if otherChildren(delegation.parent, delegation.leaf) {
+		delegation.added, delegation.leaf = nil, ""
+		return
+	}
+	if len(delegation.added) > 0 {
+		control := filepath.Join(delegation.parent, "cgroup.subtree_control")
+		_ = os.WriteFile(control, []byte("-"+strings.Join(delegation.added, " -")), 0)
+	}
+	if delegation.leaf != "" {
+		_ = os.WriteFile(filepath.Join(delegation.parent, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0)
+		_ = syscall.Rmdir(delegation.leaf)
+	}
+	delegation.added, delegation.leaf = nil, ""
+}
+
+// otherChildren reports whether parent has child cgroups besides leaf.
+func otherChildren(parent, leaf string) bool {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return true
+	}
+	for _, e := range entries {
+		if e.IsDir() && filepath.Join(parent, e.Name()) != leaf {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// apply writes the limit files.
+func (c *cgroup) apply(l Limits) error {
+	if l.MemoryBytes > 0 {
+		if err := c.write("memory.max", strconv.FormatInt(l.MemoryBytes, 10)); err != nil {
+			return err
+		}
+		// Absent without swap accounting; the memory limit still holds.
+		_ = c.write("memory.swap.max", "0")
+	}
+	if l.CPUs > 0 {
+		if err := c.write("cpu.max", cpuMax(l.CPUs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpuMax renders a CPU count as a cpu.max "quota period" line.
+func cpuMax(cpus float64) string {
+	quota := int64(cpus * cpuPeriod)
+	if quota < 1000 {
+		// The kernel rejects quotas below 1ms.
+		quota = 1000
+	}
+	return fmt.Sprintf("%d %d", quota, cpuPeriod)
+}
+
+func (c *cgroup) write(name, value string) error {
+	if err := os.WriteFile(filepath.Join(c.dir, name), []byte(value), 0); err != nil {
+		return fmt.Errorf("%w: set %s: %v", ErrCgroupUnavailable, name, err)
+	}
+	return nil
+}
+
+// attach makes the child start inside the cgroup (clone3 CLONE_INTO_CGROUP),
+// so not even its first instructions escape the limits.
+func (c *cgroup) attach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.fd.Fd())
+}
+
+// oomKilled reports whether the OOM killer fired inside the cgroup.
+func (c *cgroup) oomKilled() bool {
+	data, err := os.ReadFile(filepath.Join(c.dir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	return oomKillCount(string(data)) > 0
+}
+
+// oomKillCount parses the oom_kill counter from memory.events.
+func oomKillCount(events string) int64 {
+	scanner := bufio.NewScanner(strings.NewReader(events))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if ok && key == "oom_kill" {
+			n, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// remove kills anything left in the cgroup (daemonized grandchildren that
+// left the process group) and deletes it. Removal is best-effort: rmdir
+// fails with EBUSY until the kernel has reaped the killed processes.
+func (c *cgroup) remove() {
+	if c.fd != nil {
+		_ = c.fd.Close()
+	}
+	// cgroup.kill needs Linux 5.14; older kernels just keep stragglers.
+	_ = os.WriteFile(filepath.Join(c.dir, "cgroup.kill"), []byte("1"), 0)
+	for i := 0; i < 50; i++ {
+		if err := syscall.Rmdir(c.dir); !errors.Is(err, syscall.EBUSY) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.held {
+		c.held = false
+		releaseControllers()
+	}
+}
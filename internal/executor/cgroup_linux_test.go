@@ -0,0 +1,181 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedPath(t *testing.T) {
+	got, ok := unifiedPath("12:memory:/legacy\n0::/user.slice/ci.scope\n")
+	if !ok || got != "/user.slice/ci.scope" {
+		t.Fatalf("unifiedPath = %q, %v", got, ok)
+	}
+	if _, ok := unifiedPath("4:memory:/only-v1\n"); ok {
+		t.Fatal("v1-only hierarchy should not report a v2 path")
+	}
+}
+
+func TestCPUMax(t *testing.T) {
+	for cpus, want := range map[float64]string{2: "200000 100000", 0.5: "50000 100000", 0.001: "1000 100000"} {
+		if got := cpuMax(cpus); got != want {
+			t.Errorf("cpuMax(%v) = %q, want %q", cpus, got, want)
+		}
+	}
+}
+
+func TestOOMKillCount(t *testing.T) {
+	events := "low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\noom_group_kill 0\n"
+	if got := oomKillCount(events); got != 1 {
+		t.Fatalf("oomKillCount = %d, want 1", got)
+	}
+	if got := oomKillCount("oom 0\n"); got != 0 {
+		t.Fatalf("oomKillCount without counter = %d, want 0", got)
+	}
+}
+
+// fakeHierarchy points cgroupRoot and procSelfCgroup at plain files for a
+// process in /ci.scope, and returns that cgroup's directory. Tests using it
+// check what is written rather than kernel behaviour.
+func fakeHierarchy(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	parent := filepath.Join(root, "ci.scope")
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"cgroup.controllers", "cgroup.subtree_control"} {
+		if err := os.WriteFile(filepath.Join(parent, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proc := filepath.Join(t.TempDir(), "cgroup")
+	if err := os.WriteFile(proc, []byte("0::/ci.scope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldRoot, oldProc := cgroupRoot, procSelfCgroup
+	cgroupRoot, procSelfCgroup = root, proc
+	t.Cleanup(func() { cgroupRoot, procSelfCgroup = oldRoot, oldProc })
+	return parent
+}
+
+func TestNewCgroupWritesLimits(t *testing.T) {
+	parent := fakeHierarchy(t)
+	cg, err := newCgroup(Limits{MemoryBytes: 64 << 20, CPUs: 1.5})
+	if err != nil {
+		t.Fatalf("newCgroup: %v", err)
+	}
+	defer cg.remove()
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+	if got := read(filepath.Join(parent, "cgroup.subtree_control")); got != "+memory +cpu" {
+		t.Errorf("subtree_control = %q", got)
+	}
+	if got := read(filepath.Join(cg.dir, "memory.max")); got != "67108864" {
+		t.Errorf("memory.max = %q", got)
+	}
+	if got := read(filepath.Join(cg.dir, "cpu.max")); got != "150000 100000" {
+		t.Errorf("cpu.max = %q", got)
+	}
+}
+
+// TestNewCgroupTwice ensures command cgroups are created next to each other
+// after ci-status moved itself into its supervisor leaf, both while the
+// first one is alive (concurrent jobs) and after it was removed (retries).
+func TestNewCgroupTwice(t *testing.T) {
+	parent := fakeHierarchy(t)
+	limits := Limits{MemoryBytes: 64 << 20}
+	first, err := newCgroup(limits)
+	if err != nil {
+		t.Fatalf("first newCgroup: %v", err)
+	}
+	// What enableControllers does on EBUSY, as /proc/self/cgroup reports it.
+	if err := os.WriteFile(procSelfCgroup, []byte("0::/ci.scope/"+supervisorLeaf+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := newCgroup(limits)
+	if err != nil {
+		t.Fatalf("second newCgroup: %v", err)
+	}
+	first.remove()
+	third, err := newCgroup(limits)
+	if err != nil {
+		t.Fatalf("third newCgroup: %v", err)
+	}
+	second.remove()
+	third.remove()
+	for _, cg := range []*cgroup{first, second, third} {
+		if filepath.Dir(cg.dir) != parent {
+			t.Errorf("cgroup %s not created in %s", cg.dir, parent)
+		}
+	}
+}
+
+// TestRunWithoutCgroup ensures a host without cgroup v2 runs the command
+// without limits instead of failing it.
+func TestRunWithoutCgroup(t *testing.T) {
+	old := cgroupRoot
+	cgroupRoot = t.TempDir()
+	t.Cleanup(func() { cgroupRoot = old })
+
+	var warned error
+	e := New()
+	e.Stdout, e.Stderr = nil, nil
+	e.Limits = Limits{MemoryBytes: 64 << 20}
+	e.OnLimitsUnavailable = func(err error) { warned = err }
+	exitCode, err := e.Run(t.Context(), 0, "true", nil)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("Run = %d, %v; want the command run without limits", exitCode, err)
+	}
+	if !errors.Is(warned, ErrCgroupUnavailable) {
+		t.Fatalf("OnLimitsUnavailable got %v, want ErrCgroupUnavailable", warned)
+	}
+}
+
+func TestNewCgroupWithoutV2(t *testing.T) {
+	old := cgroupRoot
+	cgroupRoot = t.TempDir()
+	t.Cleanup(func() { cgroupRoot = old })
+
+	if _, err := newCgroup(Limits{MemoryBytes: 1 << 20}); !errors.Is(err, ErrCgroupUnavailable) {
+		t.Fatalf("err = %v, want ErrCgroupUnavailable", err)
+	}
+}
+
+// TestReleaseControllersRestores ensures the controllers enabled for
+// command cgroups are disabled again after the last one, so the runner's
+// cgroup is left as it was.
+func TestReleaseControllersRestores(t *testing.T) {
+	parent := fakeHierarchy(t)
+	control := filepath.Join(parent, "cgroup.subtree_control")
+	if err := os.WriteFile(control, []byte("cpu\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := acquireControllers([]string{"memory", "cpu"}); err != nil {
+			t.Fatalf("acquireControllers: %v", err)
+		}
+		// The fake file keeps what was last written, not the enabled set.
+		if err := os.WriteFile(control, []byte("cpu memory\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	releaseControllers()
+	if data, _ := os.ReadFile(control); string(data) != "cpu memory\n" {
+		t.Fatalf("restored while a cgroup is still in use: %q", data)
+	}
+	releaseControllers()
+	if data, _ := os.ReadFile(control); string(data) != "-memory" {
+		t.Fatalf("subtree_control = %q, want -memory", data)
+	}
+}
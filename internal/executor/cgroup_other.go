@@ -0,0 +1,20 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// cgroup is only implemented on Linux.
+type cgroup struct{}
+
+func newCgroup(Limits) (*cgroup, error) {
+	return nil, fmt.Errorf("%w: not supported on %s", ErrCgroupUnavailable, runtime.GOOS)
+}
+
+func (*cgroup) attach(*exec.Cmd) {}
+func (*cgroup) oomKilled() bool  { return false }
+func (*cgroup) remove()          {}
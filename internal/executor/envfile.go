@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseEnv validates KEY=VALUE entries (as given with --env) and returns
+// them unchanged. An entry without "=" or with an empty key is rejected
+// instead of being passed to the child, where it would be silently dropped.
+func ParseEnv(entries []string) ([]string, error) {
+	for _, kv := range entries {
+		key, _, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid environment entry %q (want KEY=VALUE)", kv)
+		}
+	}
+	return entries, nil
+}
+
+// LoadEnvFile reads a dotenv-style file: KEY=VALUE lines, blank lines and
+// "#" comments ignored, an optional "export " prefix, and values optionally
+// wrapped in matching single or double quotes (no escapes or expansion, so
+// the result does not depend on which shell would have read the file).
+func LoadEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read env file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: invalid line (want KEY=VALUE)", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read env file: %w", err)
+	}
+	return env, nil
+}
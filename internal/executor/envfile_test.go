@@ -0,0 +1,44 @@
+package executor_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"ci-status/internal/executor"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# comment\n\nA=1\nexport B = two words \nC=\"quoted # not a comment\"\nD='single'\nE=\nF=a=b\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := executor.LoadEnvFile(path)
+	if err != nil {
+		t.Fatalf("LoadEnvFile: %v", err)
+	}
+	want := []string{"A=1", "B=two words", "C=quoted # not a comment", "D=single", "E=", "F=a=b"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("A=1\nnot-an-assignment\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executor.LoadEnvFile(path); err == nil {
+		t.Fatal("expected an error for a line without =")
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	if _, err := executor.ParseEnv([]string{"A=1", "B="}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, bad := range []string{"A", "=1"} {
+		if _, err := executor.ParseEnv([]string{bad}); err == nil {
+			t.Errorf("ParseEnv(%q) should fail", bad)
+		}
+	}
+}
@@ -25,6 +25,47 @@ type Executor struct {
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+	// Shell runs the command string through the platform shell ($SHELL or
+	// sh -c on Unix, cmd /C on Windows) instead of executing it directly, so
+	// pipelines and && chains work without per-platform quoting.
+	Shell bool
+	// Dir is the command's working directory; empty means ours.
+	Dir string
+	// Env holds extra KEY=VALUE variables added to the inherited environment.
+	// Later entries win over earlier ones and over inherited variables.
+	Env []string
+	// PTY runs the command under a pseudo-terminal (Linux only) so tools that
+	// check isatty keep colors and progress output. Stdout and stderr are
+	// merged into Stdout; Stderr is unused. TERM defaults to xterm-256color
+	// when unset.
+	PTY bool
+	// PTYCols and PTYRows set the terminal size (DefaultPTYCols x
+	// DefaultPTYRows when zero).
+	PTYCols uint16
+	PTYRows uint16
+	// Prefix labels every output line ("[lint] ...") so several commands
+	// writing to one log stay readable. Output is then line-buffered and any
+	// partial last line is flushed, newline-terminated, when Run returns.
+	Prefix string
+	// Timestamps adds the time elapsed since the first Run to each line's
+	// label ("[lint 00:01:23] ..."). It implies line buffering like Prefix.
+	Timestamps bool
+	// Limits caps memory and CPU of the whole command tree through a cgroup
+	// v2 child cgroup (Linux only, needs a delegated cgroup). An OOM kill is
+	// reported as ErrOutOfMemory.
+	Limits Limits
+	// OnLimitsUnavailable is called when Limits cannot be applied (see
+	// ErrCgroupUnavailable); the command then runs without them.
+	OnLimitsUnavailable func(err error)
+
+	// start anchors Timestamps; kept across Run calls so retries continue
+	// the same clock.
+	start time.Time
+	// lastUsage is the resource usage of the most recent Run, surfaced
+	// through Result.Usage.
+	lastUsage Usage
+	// stdin forwards Stdin to PTY commands; shared by all Runs.
+	stdin *stdinPump
 }
 
 // New creates a default Executor that inherits the process standard streams.
@@ -42,8 +83,8 @@ func New() *Executor {
 // Parameters:
 // - ctx: The parent context. If cancelled, the command will be killed.
 // - timeout: If > 0, creates a derived context with this timeout.
-// - command: The executable name or path.
-// - args: Arguments for the command.
+// - command: The executable name or path (or a script when Shell is set).
+// - args: Arguments for the command (positional parameters in Shell mode).
 //
 // Returns:
 // - int: The exit code of the command (0 for success, 124 for timeout, or actual exit code).
@@ -71,17 +112,91 @@ func (e *Executor) Run(ctx context.Context, timeout time.Duration, command strin
 
 	// Own cancellation: CommandContext only kills the direct child PID, not a
 	// process group. We set the group in prepareCommand and kill it ourselves.
-	cmd := exec.Command(command, args...)
+	var cmd *exec.Cmd
+	if e.Shell {
+		cmd = shellCommand(command, args)
+	} else {
+		cmd = exec.Command(command, args...)
+	}
 	// nil Stdin would make the child read from /dev/null, which breaks
 	// pipelines and any command that expects inherited stdin.
 	cmd.Stdin = e.Stdin
 	cmd.Stdout = e.Stdout
 	cmd.Stderr = e.Stderr
+	if e.Prefix != "" || e.Timestamps {
+		// Registered before the PTY defer so terminal output reaches the
+		// line writers before they are flushed.
+		var flush func()
+		cmd.Stdout, cmd.Stderr, flush = e.labelOutput()
+		defer flush()
+	}
+	cmd.Dir = e.Dir
+	if len(e.Env) > 0 {
+		cmd.Env = append(os.Environ(), e.Env...)
+	}
 	prepareCommand(cmd)
 
+	// openPTY points the child's stdio at the terminal; keep the real
+	// destination for the copy loop.
+	output := cmd.Stdout
+	var pty *ptySession
+	if e.PTY {
+		cols, rows := e.PTYCols, e.PTYRows
+		if cols == 0 {
+			cols = DefaultPTYCols
+		}
+		if rows == 0 {
+			rows = DefaultPTYRows
+		}
+		var err error
+		if pty, err = openPTY(cmd, cols, rows); err != nil {
+			return 0, fmt.Errorf("start command: %w", err)
+		}
+		if os.Getenv("TERM") == "" {
+			if cmd.Env == nil {
+				cmd.Env = os.Environ()
+			}
+			cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+		}
+	}
+
+	var cg *cgroup
+	if e.Limits.active() {
+		var err error
+		if cg, err = newCgroup(e.Limits); err != nil {
+			// A host without cgroup delegation is no reason to fail the
+			// command itself.
+			if e.OnLimitsUnavailable != nil {
+				e.OnLimitsUnavailable(err)
+			}
+		} else {
+			cg.attach(cmd)
+			// Deferred before pty.finish so it runs after terminal output is
+			// drained.
+			defer cg.remove()
+		}
+	}
+
 	if err := cmd.Start(); err != nil {
+		if pty != nil {
+			pty.abort()
+		}
+		e.lastUsage = Usage{}
 		return 0, fmt.Errorf("start command: %w", err)
 	}
+	if pty != nil {
+		if e.stdin == nil && e.Stdin != nil {
+			e.stdin = newStdinPump(e.Stdin)
+		}
+		pty.started(e.stdin, output)
+		// Deferred so buffered terminal output is written before Run returns.
+		defer pty.finish()
+	}
+
+	// Both select branches wait for the process, so ProcessState is final.
+	defer func() {
+		e.lastUsage = usageOf(cmd.ProcessState)
+	}()
 
 	waitErr := make(chan error, 1)
 	go func() {
@@ -95,7 +210,10 @@ func (e *Executor) Run(ctx context.Context, timeout time.Duration, command strin
 		}
 		var exitError *exec.ExitError
 		if errors.As(err, &exitError) {
-			return exitError.ExitCode(), nil
+			if cg != nil && cg.oomKilled() {
+				return exitCode(exitError), ErrOutOfMemory
+			}
+			return exitCode(exitError), nil
 		}
 		return 1, fmt.Errorf("command execution failed: %w", err)
 	case <-ctx.Done():
@@ -106,6 +224,35 @@ func (e *Executor) Run(ctx context.Context, timeout time.Duration, command strin
 	}
 }
 
+// labelOutput wraps Stdout and Stderr in line writers labelled with Prefix
+// and, with Timestamps, the elapsed time. The returned func flushes partial
+// lines. Nil streams stay nil so the child keeps reading/writing /dev/null.
+func (e *Executor) labelOutput() (stdout, stderr io.Writer, flush func()) {
+	var writers []*LineWriter
+	wrap := func(w io.Writer) io.Writer {
+		if w == nil {
+			return nil
+		}
+		var lw *LineWriter
+		if e.Timestamps {
+			if e.start.IsZero() {
+				e.start = time.Now()
+			}
+			lw = NewStampedLineWriter(w, e.Prefix, e.start)
+		} else {
+			lw = NewLineWriter(w, "["+e.Prefix+"] ")
+		}
+		writers = append(writers, lw)
+		return lw
+	}
+	stdout, stderr = wrap(e.Stdout), wrap(e.Stderr)
+	return stdout, stderr, func() {
+		for _, lw := range writers {
+			_ = lw.Flush()
+		}
+	}
+}
+
 // outcomeAfterStop maps a Wait result after timeout/cancel kill.
 //
 // select may choose the ctx.Done branch even when waitErr is also ready
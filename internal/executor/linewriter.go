@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LineWriter prefixes every line written through it, e.g. "[lint] ".
+// Output is buffered until a newline so concurrent commands sharing one
+// terminal never split a line; Flush emits whatever partial line remains.
+// A carriage return also ends a line, so progress bars redrawing one line
+// are shown as they update, and a line longer than maxLineBuffer is written
+// in pieces rather than buffered without bound.
+type LineWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+	// afterCR is set when the last line written ended in '\r', so a '\n'
+	// completing "\r\n" in the next Write is not taken for an empty line.
+	afterCR bool
+	// label and start are set by NewStampedLineWriter; prefix is then
+	// rebuilt on every write with the elapsed time.
+	label   string
+	start   time.Time
+	stamped bool
+}
+
+// maxLineBuffer caps the partial line LineWriter holds back; a longer line
+// is ended with a newline and continues under a new prefix.
+const maxLineBuffer = 64 << 10
+
+// NewLineWriter wraps w, writing prefix before each line.
+func NewLineWriter(w io.Writer, prefix string) *LineWriter {
+	return &LineWriter{w: w, prefix: prefix}
+}
+
+// NewStampedLineWriter wraps w, writing "[label HH:MM:SS] " before each line,
+// where the time is elapsed since start. An empty label yields "[HH:MM:SS] ".
+// Lines completed by the same Write share one timestamp.
+func NewStampedLineWriter(w io.Writer, label string, start time.Time) *LineWriter {
+	return &LineWriter{w: w, label: label, start: start, stamped: true}
+}
+
+// header returns the text written before each line. Callers hold l.mu.
+func (l *LineWriter) header() string {
+	if !l.stamped {
+		return l.prefix
+	}
+	elapsed := time.Since(l.start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	secs := int64(elapsed / time.Second)
+	stamp := fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	if l.label == "" {
+		return "[" + stamp + "] "
+	}
+	return "[" + l.label + " " + stamp + "] "
+}
+
+// Write buffers p and forwards every complete line (ended by '\n', '\r' or
+// "\r\n") in a single Write call to the underlying writer. It always reports
+// len(p) on success so callers such as io.Copy do not treat buffering as a
+// short write.
+func (l *LineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	prefix := l.header()
+	var out []byte
+	rest := l.buf
+	if l.afterCR && len(rest) > 0 && rest[0] == '\n' {
+		out = append(out, '\n')
+		rest = rest[1:]
+	}
+	for {
+		i := bytes.IndexAny(rest, "\r\n")
+		if i < 0 || i >= maxLineBuffer {
+			if len(rest) < maxLineBuffer {
+				break
+			}
+			out = append(out, prefix...)
+			out = append(out, rest[:maxLineBuffer]...)
+			out = append(out, '\n')
+			rest = rest[maxLineBuffer:]
+			continue
+		}
+		if rest[i] == '\r' && i+1 < len(rest) && rest[i+1] == '\n' {
+			i++
+		}
+		out = append(out, prefix...)
+		out = append(out, rest[:i+1]...)
+		rest = rest[i+1:]
+	}
+	if len(out) == 0 {
+		return len(p), nil
+	}
+	l.afterCR = out[len(out)-1] == '\r'
+	l.buf = append(l.buf[:0], rest...)
+
+	if _, err := l.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line, terminated with a newline so the
+// next writer's prefix starts on its own line.
+func (l *LineWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) == 0 {
+		return nil
+	}
+	prefix := l.header()
+	out := make([]byte, 0, len(prefix)+len(l.buf)+1)
+	out = append(out, prefix...)
+	out = append(out, l.buf...)
+	out = append(out, '\n')
+	l.buf = l.buf[:0]
+	l.afterCR = false
+	_, err := l.w.Write(out)
+	return err
+}
+
+// LockedWriter serializes writes to W. Wrap a shared destination (os.Stdout)
+// with it when several LineWriters write to it from different goroutines.
+type LockedWriter struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+// Write forwards p to W while holding the lock.
+func (l *LockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.W.Write(p)
+}
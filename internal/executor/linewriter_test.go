@@ -0,0 +1,110 @@
+package executor_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"ci-status/internal/executor"
+)
+
+func TestLineWriterPrefixesCompleteLines(t *testing.T) {
+	var out bytes.Buffer
+	w := executor.NewLineWriter(&out, "[lint] ")
+
+	if _, err := w.Write([]byte("one\ntw")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := out.String(); got != "[lint] one\n" {
+		t.Fatalf("after first write got %q, want only the complete line", got)
+	}
+	if _, err := w.Write([]byte("o\nthree")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := out.String(); got != "[lint] one\n[lint] two\n" {
+		t.Fatalf("partial line not joined: %q", got)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := out.String(); got != "[lint] one\n[lint] two\n[lint] three\n" {
+		t.Fatalf("Flush should emit the partial line with a newline, got %q", got)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("second Flush: %v", err)
+	}
+	if got := out.String(); got != "[lint] one\n[lint] two\n[lint] three\n" {
+		t.Fatalf("empty Flush should write nothing, got %q", got)
+	}
+}
+
+func TestLineWriterKeepsEmptyLines(t *testing.T) {
+	var out bytes.Buffer
+	w := executor.NewLineWriter(&out, "> ")
+	if _, err := w.Write([]byte("a\n\nb\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := out.String(); got != "> a\n> \n> b\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestLineWriterEndsLinesAtCarriageReturn(t *testing.T) {
+	var out bytes.Buffer
+	w := executor.NewLineWriter(&out, "[dl] ")
+	if _, err := w.Write([]byte("10%\r20%\r")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got, want := out.String(), "[dl] 10%\r[dl] 20%\r"; got != want {
+		t.Fatalf("progress not written before a newline: got %q, want %q", got, want)
+	}
+	// "\r\n" split across writes is still one line ending.
+	if _, err := w.Write([]byte("\ndone\r\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got, want := out.String(), "[dl] 10%\r[dl] 20%\r\n[dl] done\r\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestLineWriterSplitsOverlongLines(t *testing.T) {
+	var out bytes.Buffer
+	w := executor.NewLineWriter(&out, "> ")
+	long := bytes.Repeat([]byte("x"), 64<<10)
+	if _, err := w.Write(append(long, "tail"...)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got, want := out.String(), "> "+string(long)+"\n"; got != want {
+		t.Fatalf("got %d bytes, want the first %d written as a line", len(got), len(want))
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "\n> tail\n") {
+		t.Fatalf("remainder not flushed under a new prefix: %q", out.String()[out.Len()-20:])
+	}
+}
+
+func TestStampedLineWriterShowsElapsedTime(t *testing.T) {
+	var out bytes.Buffer
+	w := executor.NewStampedLineWriter(&out, "lint", time.Now().Add(-83*time.Second))
+	if _, err := w.Write([]byte("one\npart")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got, want := out.String(), "[lint 00:01:23] one\n[lint 00:01:23] part\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	out.Reset()
+	w = executor.NewStampedLineWriter(&out, "", time.Now().Add(-2*time.Hour))
+	if _, err := w.Write([]byte("x\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got, want := out.String(), "[02:00:00] x\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
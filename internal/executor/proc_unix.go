@@ -30,3 +30,13 @@ func killCommand(cmd *exec.Cmd) {
 		return
 	}
 }
+
+// exitCode is the command's exit status, or 128+signal when a signal killed
+// it (137 for SIGKILL, e.g. the OOM killer), as shells report it.
+// ExitCode alone says -1, which os.Exit would turn into 255.
+func exitCode(err *exec.ExitError) int {
+	if ws, ok := err.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return err.ExitCode()
+}
@@ -68,3 +68,19 @@ func TestTimeoutKillsProcessGroup(t *testing.T) {
 		time.Sleep(20 * time.Millisecond)
 	}
 }
+
+// TestSignalExitCode ensures a command killed by a signal reports 128+signal
+// like a shell, not -1 (exit status 255).
+func TestSignalExitCode(t *testing.T) {
+	e := executor.New()
+	e.Stdout = &bytes.Buffer{}
+	e.Stderr = &bytes.Buffer{}
+
+	exitCode, err := e.Run(t.Context(), 0, "sh", []string{"-c", "kill -KILL $$"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := 128 + int(syscall.SIGKILL); exitCode != want {
+		t.Fatalf("exit code = %d, want %d", exitCode, want)
+	}
+}
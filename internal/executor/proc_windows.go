@@ -30,3 +30,8 @@ func killCommand(cmd *exec.Cmd) {
 		return
 	}
 }
+
+// exitCode is the command's exit status; Windows has no signal deaths.
+func exitCode(err *exec.ExitError) int {
+	return err.ExitCode()
+}
@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrPTYUnsupported is returned by Run when PTY is requested on a platform
+// without pseudo-terminal support in this package.
+var ErrPTYUnsupported = errors.New("pseudo-terminal mode is only supported on Linux")
+
+// Default pseudo-terminal size used when Executor.PTYCols/PTYRows are zero.
+const (
+	DefaultPTYCols = 120
+	DefaultPTYRows = 40
+)
+
+// ptyDrainGrace bounds how long Run waits for buffered terminal output after
+// the command exited. A background grandchild that keeps the terminal open
+// would otherwise block Run forever.
+const ptyDrainGrace = 2 * time.Second
+
+// veof is the terminal's default end-of-file character (^D); openPTY
+// leaves the terminal in its default canonical mode.
+const veof = 0x04
+
+// ptySession is the parent side of a pseudo-terminal wired to a command.
+type ptySession struct {
+	master *os.File
+	// slave is the child's end; the parent closes its copy once the child
+	// started so reads on master end (EIO) when the child's copies close.
+	slave  *os.File
+	copied chan struct{}
+	// done stops forwarding stdin when the command finished.
+	done chan struct{}
+}
+
+// started releases the parent's slave fd and starts copying terminal output
+// to stdout and stdin (if any) to the terminal until finish.
+func (p *ptySession) started(stdin *stdinPump, stdout io.Writer) {
+	_ = p.slave.Close()
+	p.done = make(chan struct{})
+	go func() {
+		// Read ends with EIO (Linux) when the last slave fd closes; that is
+		// the normal end of output, not an error worth reporting.
+		_, _ = io.Copy(stdout, p.master)
+		close(p.copied)
+	}()
+	if stdin != nil {
+		go stdin.forward(p.master, p.done)
+	}
+}
+
+// abort releases both ends when the command never started.
+func (p *ptySession) abort() {
+	_ = p.slave.Close()
+	_ = p.master.Close()
+}
+
+// finish waits (bounded by ptyDrainGrace) for output to drain, then closes
+// the master so the copy goroutines end.
+func (p *ptySession) finish() {
+	close(p.done)
+	select {
+	case <-p.copied:
+	case <-time.After(ptyDrainGrace):
+	}
+	_ = p.master.Close()
+	<-p.copied
+}
+
+// stdinPump reads an Executor's Stdin once for all Runs and hands it to the
+// terminal of the running one. A copy per Run would stay blocked reading
+// after its command ended (--retries) and swallow input meant for the next.
+type stdinPump struct {
+	chunks chan []byte
+}
+
+func newStdinPump(r io.Reader) *stdinPump {
+	p := &stdinPump{chunks: make(chan []byte)}
+	go func() {
+		defer close(p.chunks)
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := r.Read(buf)
+			if n > 0 {
+				p.chunks <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// forward writes stdin to w until done is closed or stdin ends. Chunks read
+// while no command runs wait for the next forward. At the end of stdin the
+// terminal gets its end-of-file character, so commands reading until EOF
+// (echo data | ci-status run --pty -- cat) finish.
+func (p *stdinPump) forward(w io.Writer, done <-chan struct{}) {
+	atLineStart := true
+	for {
+		select {
+		case <-done:
+			return
+		case chunk, ok := <-p.chunks:
+			if !ok {
+				// VEOF only ends input at the start of a line; after a
+				// partial line the first one just submits it.
+				eof := []byte{veof}
+				if !atLineStart {
+					eof = append(eof, veof)
+				}
+				_, _ = w.Write(eof)
+				return
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			atLineStart = chunk[len(chunk)-1] == '\n'
+		}
+	}
+}
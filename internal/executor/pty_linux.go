@@ -0,0 +1,76 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// openPTY allocates a pseudo-terminal of the given size and makes it the
+// command's stdin/stdout/stderr and controlling terminal.
+//
+// Must run after prepareCommand: the child becomes a session leader (Setsid)
+// instead of joining a new process group (Setpgid), because setpgid fails
+// for session leaders. Setsid also makes the child's PID its process group
+// ID, so killCommand's group kill keeps working unchanged.
+func openPTY(cmd *exec.Cmd, cols, rows uint16) (*ptySession, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open pty: %w", err)
+	}
+
+	var ptn uint32
+	unlock := int32(0)
+	ws := struct{ Row, Col, X, Y uint16 }{Row: rows, Col: cols}
+	// Control keeps the fd in non-blocking (poller) mode, unlike Fd(), so
+	// closing master later still interrupts a pending Read.
+	raw, err := master.SyscallConn()
+	if err == nil {
+		ctlErr := raw.Control(func(fd uintptr) {
+			if err = ioctl(fd, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+				return
+			}
+			if err = ioctl(fd, syscall.TIOCGPTN, unsafe.Pointer(&ptn)); err != nil {
+				return
+			}
+			err = ioctl(fd, syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+		})
+		if err == nil {
+			err = ctlErr
+		}
+	}
+	if err != nil {
+		_ = master.Close()
+		return nil, fmt.Errorf("configure pty: %w", err)
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", ptn), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, fmt.Errorf("open pty slave: %w", err)
+	}
+
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0 // child's stdin, i.e. the slave
+
+	return &ptySession{master: master, slave: slave, copied: make(chan struct{})}, nil
+}
+
+func ioctl(fd, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package executor_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"ci-status/internal/executor"
+)
+
+func requirePTY(t *testing.T) {
+	t.Helper()
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no /dev/ptmx in this environment")
+	}
+}
+
+// TestPTYModeIsATerminal ensures the child sees a terminal of the configured
+// size on all three standard streams and its output still reaches Stdout.
+func TestPTYModeIsATerminal(t *testing.T) {
+	requirePTY(t)
+	e := executor.New()
+	var stdout bytes.Buffer
+	e.Stdin = nil
+	e.Stdout = &stdout
+	e.PTY = true
+	e.PTYCols = 100
+	e.PTYRows = 30
+
+	script := `[ -t 0 ] && [ -t 1 ] && [ -t 2 ] && echo is-tty; stty size; echo to-stderr >&2; exit 3`
+	exitCode, err := e.Run(t.Context(), 0, "sh", []string{"-c", script})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exitCode != 3 {
+		t.Fatalf("exit code = %d, want 3", exitCode)
+	}
+	out := strings.ReplaceAll(stdout.String(), "\r\n", "\n")
+	for _, want := range []string{"is-tty\n", "30 100\n", "to-stderr\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output %q should contain %q", out, want)
+		}
+	}
+}
+
+// TestPTYModeTimeoutKillsTree ensures Setsid (used instead of Setpgid under a
+// pseudo-terminal) still lets killCommand tear down the whole process group.
+func TestPTYModeTimeoutKillsTree(t *testing.T) {
+	requirePTY(t)
+	e := executor.New()
+	e.Stdin = nil
+	e.Stdout = &bytes.Buffer{}
+	e.PTY = true
+
+	start := time.Now()
+	exitCode, err := e.Run(t.Context(), 200*time.Millisecond, "sh", []string{"-c", "sleep 60 & wait"})
+	if !errors.Is(err, executor.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got exit=%d err=%v", exitCode, err)
+	}
+	// The background sleep holds the terminal open; Run must not wait for it.
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("Run took %s, process group was not killed", elapsed)
+	}
+}
+
+// TestPTYModeStdinEOF ensures the end of piped stdin reaches the command as
+// end-of-file, with and without a trailing newline.
+func TestPTYModeStdinEOF(t *testing.T) {
+	requirePTY(t)
+	for _, input := range []string{"piped line\n", "no newline"} {
+		e := executor.New()
+		var stdout bytes.Buffer
+		e.Stdin = strings.NewReader(input)
+		e.Stdout = &stdout
+		e.PTY = true
+
+		exitCode, err := e.Run(t.Context(), 5*time.Second, "cat", nil)
+		if err != nil || exitCode != 0 {
+			t.Fatalf("cat with stdin %q = %d, %v; output %q", input, exitCode, err, stdout.String())
+		}
+		if !strings.Contains(stdout.String(), strings.TrimSuffix(input, "\n")) {
+			t.Fatalf("output %q should echo %q", stdout.String(), input)
+		}
+	}
+}
+
+// TestPTYModeStdinAcrossRuns ensures input written after one Run ended
+// reaches the next one (retries) instead of a stale copy from the first.
+func TestPTYModeStdinAcrossRuns(t *testing.T) {
+	requirePTY(t)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = r.Close()
+		_ = w.Close()
+	})
+	e := executor.New()
+	var stdout bytes.Buffer
+	e.Stdin = r
+	e.Stdout = &stdout
+	e.PTY = true
+
+	if _, err := e.Run(t.Context(), 0, "true", nil); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if _, err := w.WriteString("second\n"); err != nil {
+		t.Fatal(err)
+	}
+	// Checked through the exit code: output written just before exit can be
+	// lost when the terminal closes.
+	exitCode, err := e.Run(t.Context(), 5*time.Second, "sh", []string{"-c", `read line && test "$line" = second`})
+	if err != nil || exitCode != 0 {
+		t.Fatalf("second run = %d, %v; want the line written after the first run; output %q", exitCode, err, stdout.String())
+	}
+}
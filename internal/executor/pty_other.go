@@ -0,0 +1,10 @@
+//go:build !linux
+
+package executor
+
+import "os/exec"
+
+// openPTY is only implemented on Linux.
+func openPTY(cmd *exec.Cmd, cols, rows uint16) (*ptySession, error) {
+	return nil, ErrPTYUnsupported
+}
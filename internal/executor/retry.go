@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy controls how RunWithRetry reruns a failing command.
+// The zero value runs the command exactly once.
+type RetryPolicy struct {
+	// Retries is the number of extra attempts after the first one.
+	Retries int
+	// Delay is the pause between attempts. Cancelling ctx cuts it short.
+	Delay time.Duration
+	// ShouldRetry decides whether an exit code deserves another attempt.
+	// Nil retries any non-zero exit code.
+	ShouldRetry func(exitCode int) bool
+	// RetryTimeouts also retries attempts that hit the per-attempt timeout.
+	// Off by default so a hung command does not multiply the job duration.
+	RetryTimeouts bool
+	// OnRetry is called before each retry with the 1-based attempt about to
+	// start, the total number of attempts and the exit code that triggered it.
+	// It runs before the delay so status updates are visible while waiting.
+	OnRetry func(attempt, total, lastExitCode int)
+}
+
+// Result summarizes a RunWithRetry call.
+type Result struct {
+	// ExitCode is the exit code of the last attempt (same conventions as Run).
+	ExitCode int
+	// Attempts is how many times the command was started (at least 1).
+	Attempts int
+	// Duration is the wall time of all attempts, including retry delays.
+	Duration time.Duration
+	// Usage is the resource usage of the last attempt.
+	Usage Usage
+}
+
+// Retries is the number of attempts beyond the first.
+func (r Result) Retries() int {
+	if r.Attempts <= 1 {
+		return 0
+	}
+	return r.Attempts - 1
+}
+
+// RunWithRetry runs the command like Run, rerunning it while policy allows.
+// timeout applies to each attempt, not to the whole sequence.
+//
+// Start failures and cancellation are never retried: the former will not fix
+// itself and the latter means the caller asked us to stop. The returned error
+// is the last attempt's error (or a cancel error if ctx ended during a delay).
+func (e *Executor) RunWithRetry(ctx context.Context, timeout time.Duration, policy RetryPolicy, command string, args []string) (Result, error) {
+	// Cover the delays between attempts too; Run only listens while a child runs.
+	ctx, stop := withSignalCancel(ctx)
+	defer stop()
+
+	total := policy.Retries + 1
+	var res Result
+	began := time.Now()
+	for attempt := 1; ; attempt++ {
+		res.Attempts = attempt
+		code, err := e.Run(ctx, timeout, command, args)
+		res.ExitCode = code
+		res.Usage = e.lastUsage
+		res.Duration = time.Since(began)
+		if attempt >= total || ctx.Err() != nil || !policy.retryable(code, err) {
+			return res, err
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, total, code)
+		}
+		if policy.Delay > 0 {
+			timer := time.NewTimer(policy.Delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return Result{ExitCode: 1, Attempts: attempt, Duration: time.Since(began), Usage: res.Usage}, fmt.Errorf("command cancelled: %w", ctx.Err())
+			}
+		}
+	}
+}
+
+// retryable reports whether an attempt's outcome qualifies for another run.
+func (p RetryPolicy) retryable(exitCode int, err error) bool {
+	switch {
+	case err == nil:
+		if exitCode == 0 {
+			return false
+		}
+		return p.ShouldRetry == nil || p.ShouldRetry(exitCode)
+	case errors.Is(err, ErrTimeout):
+		return p.RetryTimeouts
+	default:
+		return false
+	}
+}
@@ -0,0 +1,114 @@
+package executor_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ci-status/internal/executor"
+)
+
+// flakyScript fails until it has been started $2 times, counting attempts in $1.
+const flakyScript = `n=$(cat "$1" 2>/dev/null || echo 0); n=$((n+1)); echo $n >"$1"; [ "$n" -ge "$2" ]`
+
+func TestRunWithRetry_PassesAfterRetries(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "count")
+	e := executor.New()
+	e.Stdout = &bytes.Buffer{}
+	e.Stderr = &bytes.Buffer{}
+
+	var retried []int
+	policy := executor.RetryPolicy{
+		Retries: 3,
+		OnRetry: func(attempt, total, lastExitCode int) {
+			if total != 4 {
+				t.Errorf("total = %d, want 4", total)
+			}
+			if lastExitCode != 1 {
+				t.Errorf("lastExitCode = %d, want 1", lastExitCode)
+			}
+			retried = append(retried, attempt)
+		},
+	}
+
+	res, err := e.RunWithRetry(t.Context(), 0, policy, "sh", []string{"-c", flakyScript, "sh", counter, "3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != 0 {
+		t.Fatalf("exit code = %d, want 0", res.ExitCode)
+	}
+	if res.Attempts != 3 || res.Retries() != 2 {
+		t.Fatalf("attempts = %d retries = %d, want 3 and 2", res.Attempts, res.Retries())
+	}
+	if len(retried) != 2 || retried[0] != 2 || retried[1] != 3 {
+		t.Fatalf("OnRetry attempts = %v, want [2 3]", retried)
+	}
+}
+
+func TestRunWithRetry_GivesUp(t *testing.T) {
+	e := executor.New()
+	e.Stdout = &bytes.Buffer{}
+	e.Stderr = &bytes.Buffer{}
+
+	res, err := e.RunWithRetry(t.Context(), 0, executor.RetryPolicy{Retries: 2}, "false", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != 1 || res.Attempts != 3 {
+		t.Fatalf("got exit=%d attempts=%d, want exit=1 attempts=3", res.ExitCode, res.Attempts)
+	}
+}
+
+func TestRunWithRetry_ShouldRetryFiltersCodes(t *testing.T) {
+	e := executor.New()
+	e.Stdout = &bytes.Buffer{}
+	e.Stderr = &bytes.Buffer{}
+
+	policy := executor.RetryPolicy{
+		Retries:     2,
+		ShouldRetry: func(code int) bool { return code == 137 },
+	}
+	res, err := e.RunWithRetry(t.Context(), 0, policy, "false", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 (exit 1 is not retryable)", res.Attempts)
+	}
+}
+
+func TestRunWithRetry_TimeoutsOnlyWhenEnabled(t *testing.T) {
+	e := executor.New()
+	e.Stdout = &bytes.Buffer{}
+	e.Stderr = &bytes.Buffer{}
+
+	res, err := e.RunWithRetry(t.Context(), 50*time.Millisecond, executor.RetryPolicy{Retries: 1}, "sleep", []string{"1"})
+	if !errors.Is(err, executor.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if res.Attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 without RetryTimeouts", res.Attempts)
+	}
+
+	res, err = e.RunWithRetry(t.Context(), 50*time.Millisecond, executor.RetryPolicy{Retries: 1, RetryTimeouts: true}, "sleep", []string{"1"})
+	if !errors.Is(err, executor.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if res.Attempts != 2 {
+		t.Fatalf("attempts = %d, want 2 with RetryTimeouts", res.Attempts)
+	}
+}
+
+func TestRunWithRetry_StartFailureNotRetried(t *testing.T) {
+	e := executor.New()
+	res, err := e.RunWithRetry(t.Context(), 0, executor.RetryPolicy{Retries: 3}, "ci-status-definitely-missing", nil)
+	if err == nil {
+		t.Fatal("expected start error")
+	}
+	if res.Attempts != 1 || res.ExitCode != 0 {
+		t.Fatalf("got exit=%d attempts=%d, want exit=0 attempts=1", res.ExitCode, res.Attempts)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+)
+
+// shellCommand builds a command that runs script through the user's shell.
+// $SHELL is honoured so scripts behave like they do in the CI YAML's own
+// shell; sh is the fallback for minimal containers. Extra args become the
+// script's positional parameters ($1, $2, ...), with "sh" as $0.
+func shellCommand(script string, args []string) *exec.Cmd {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+	return exec.Command(shell, append([]string{"-c", script, "sh"}, args...)...)
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package executor_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"ci-status/internal/executor"
+)
+
+func TestExecutorShellMode(t *testing.T) {
+	e := executor.New()
+	var stdout bytes.Buffer
+	e.Stdout = &stdout
+	e.Stderr = &bytes.Buffer{}
+	e.Shell = true
+
+	exitCode, err := e.Run(t.Context(), 0, `echo "$1" && exit 3`, []string{"arg"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exitCode != 3 {
+		t.Fatalf("exit code = %d, want 3", exitCode)
+	}
+	if got := stdout.String(); got != "arg\n" {
+		t.Fatalf("stdout = %q, want positional arg echoed", got)
+	}
+}
+
+func TestExecutorPrefixFlushesPartialLines(t *testing.T) {
+	e := executor.New()
+	var stdout, stderr bytes.Buffer
+	e.Stdout = &stdout
+	e.Stderr = &stderr
+	e.Shell = true
+	e.Prefix = "lint"
+
+	if _, err := e.Run(t.Context(), 0, `echo one; printf two; printf err >&2`, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := stdout.String(), "[lint] one\n[lint] two\n"; got != want {
+		t.Fatalf("stdout = %q, want %q", got, want)
+	}
+	if got, want := stderr.String(), "[lint] err\n"; got != want {
+		t.Fatalf("stderr = %q, want %q", got, want)
+	}
+}
+
+func TestExecutorDirAndEnv(t *testing.T) {
+	e := executor.New()
+	var stdout bytes.Buffer
+	e.Stdout = &stdout
+	e.Stderr = &bytes.Buffer{}
+	e.Shell = true
+	e.Dir = t.TempDir()
+	e.Env = []string{"CI_STATUS_TEST=a", "CI_STATUS_TEST=b"}
+
+	if _, err := e.Run(t.Context(), 0, `pwd -P; echo "$CI_STATUS_TEST"`, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := filepath.EvalSymlinks(e.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != want+"\nb\n" {
+		t.Fatalf("stdout = %q, want the directory and the last Env entry", got)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand builds a command that runs script through cmd.exe.
+// The command line is passed verbatim via CmdLine: Go's default argv quoting
+// escapes the quotes cmd /C expects, which mangles pipelines and && chains.
+// /S makes cmd strip only the outer quotes. Extra args are ignored because
+// cmd has no positional parameters for /C scripts.
+func shellCommand(script string, args []string) *exec.Cmd {
+	comspec := os.Getenv("ComSpec")
+	if comspec == "" {
+		comspec = "cmd.exe"
+	}
+	cmd := exec.Command(comspec)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: comspec + ` /S /C "` + script + `"`,
+	}
+	return cmd
+}
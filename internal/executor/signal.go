@@ -11,3 +11,9 @@ import (
 func withSignalCancel(ctx context.Context) (context.Context, context.CancelFunc) {
 	return signal.NotifyContext(ctx, interruptSignals...)
 }
+
+// WithSignalCancel is withSignalCancel for long-running commands outside
+// this package ('ci-status serve'), so they stop on the same signals.
+func WithSignalCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withSignalCancel(ctx)
+}
@@ -0,0 +1,38 @@
+package executor
+
+// StatusEnv describes the status context a command reports to, exported to
+// it as CI_STATUS_* variables so scripts can tell which context, commit and
+// forge ci-status resolved. Empty fields are omitted.
+//
+// Commit, Forge and TargetURL use the variables ci-status binds to its own
+// --commit, --forge and --url flags, so a nested ci-status call reports to
+// the same place; Sink carries the rest of that target.
+type StatusEnv struct {
+	Context   string
+	Commit    string
+	Forge     string
+	TargetURL string
+	// Channel is the status channel file the command may append
+	// "description: ..." lines to (see package channel).
+	Channel string
+	// Sink holds further CI_STATUS_* KEY=VALUE entries for the forge flags
+	// (--repo, --api-url, ...) the statuses are reported with.
+	Sink []string
+}
+
+// Environ renders the variables as KEY=VALUE entries for Executor.Env.
+func (s StatusEnv) Environ() []string {
+	var env []string
+	for _, kv := range [][2]string{
+		{"CI_STATUS_CONTEXT", s.Context},
+		{"CI_STATUS_COMMIT", s.Commit},
+		{"CI_STATUS_FORGE", s.Forge},
+		{"CI_STATUS_URL", s.TargetURL},
+		{"CI_STATUS_CHANNEL", s.Channel},
+	} {
+		if kv[1] != "" {
+			env = append(env, kv[0]+"="+kv[1])
+		}
+	}
+	return append(env, s.Sink...)
+}
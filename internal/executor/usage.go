@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"os"
+	"time"
+)
+
+// Usage is the resource usage of a finished command, including the children
+// it waited for. CPU times are available everywhere; MaxRSS and the block
+// counters are only filled in on Linux and are zero elsewhere.
+type Usage struct {
+	// UserCPU and SystemCPU are the CPU time spent in user and kernel mode.
+	UserCPU   time.Duration
+	SystemCPU time.Duration
+	// MaxRSS is the peak resident set size in bytes of the largest process
+	// in the tree (the kernel does not sum it across children).
+	MaxRSS int64
+	// InBlocks and OutBlocks count filesystem block I/O operations.
+	InBlocks  int64
+	OutBlocks int64
+}
+
+// usageOf extracts Usage from a finished process. nil (the process never
+// started or Wait failed early) yields the zero Usage.
+func usageOf(state *os.ProcessState) Usage {
+	if state == nil {
+		return Usage{}
+	}
+	u := Usage{
+		UserCPU:   state.UserTime(),
+		SystemCPU: state.SystemTime(),
+	}
+	addPlatformUsage(&u, state)
+	return u
+}
@@ -0,0 +1,18 @@
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// addPlatformUsage fills in the rusage fields Go has no portable accessor for.
+// Linux reports ru_maxrss in kilobytes.
+func addPlatformUsage(u *Usage, state *os.ProcessState) {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return
+	}
+	u.MaxRSS = int64(ru.Maxrss) * 1024
+	u.InBlocks = int64(ru.Inblock)
+	u.OutBlocks = int64(ru.Oublock)
+}
@@ -0,0 +1,25 @@
+package executor_test
+
+import (
+	"bytes"
+	"testing"
+
+	"ci-status/internal/executor"
+)
+
+func TestRunWithRetryReportsUsage(t *testing.T) {
+	e := executor.New()
+	e.Stdout = &bytes.Buffer{}
+	e.Stderr = &bytes.Buffer{}
+
+	res, err := e.RunWithRetry(t.Context(), 0, executor.RetryPolicy{}, "sh", []string{"-c", "exit 0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Usage.MaxRSS <= 0 {
+		t.Fatalf("MaxRSS = %d, want the child's peak RSS", res.Usage.MaxRSS)
+	}
+	if res.Duration <= 0 {
+		t.Fatalf("Duration = %v, want > 0", res.Duration)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package executor
+
+import "os"
+
+// addPlatformUsage is a no-op: rusage units and fields differ between
+// platforms, so only the portable CPU times are reported.
+func addPlatformUsage(u *Usage, state *os.ProcessState) {}
@@ -0,0 +1,75 @@
+package forge
+
+import (
+	"context"
+	"sync"
+)
+
+// DedupClient wraps a client and drops updates identical to the last status
+// it posted for the same commit and context (state, description and target
+// URL all equal), so repeated progress or retry posts cost no API calls.
+// Failed posts are not remembered, so they are sent again. Posts for the
+// same commit and context are serialized, so the remembered status is always
+// the one the forge received last.
+type DedupClient struct {
+	Client ForgeClient
+
+	mu    sync.Mutex
+	last  map[dedupKey]StatusOpts
+	locks map[dedupKey]*sync.Mutex
+}
+
+type dedupKey struct{ commit, context string }
+
+// NewDedupClient wraps client; a nil client stays nil so callers can keep
+// checking for "no forge".
+func NewDedupClient(client ForgeClient) ForgeClient {
+	if client == nil {
+		return nil
+	}
+	return &DedupClient{Client: client}
+}
+
+// SetStatus forwards opts unless they repeat the last posted status.
+func (c *DedupClient) SetStatus(ctx context.Context, opts StatusOpts) error {
+	key := dedupKey{opts.Commit, opts.Context}
+	lock := c.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.mu.Lock()
+	last, ok := c.last[key]
+	c.mu.Unlock()
+	if ok && last == opts {
+		return nil
+	}
+	if err := c.Client.SetStatus(ctx, opts); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last[key] = opts
+	return nil
+}
+
+// lock returns the mutex serializing posts for key.
+func (c *DedupClient) lock(key dedupKey) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.locks == nil {
+		c.last = map[dedupKey]StatusOpts{}
+		c.locks = map[dedupKey]*sync.Mutex{}
+	}
+	l, ok := c.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[key] = l
+	}
+	return l
+}
+
+// ForgeName implements Namer for the wrapped client.
+func (c *DedupClient) ForgeName() string {
+	return Name(c.Client)
+}
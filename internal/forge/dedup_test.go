@@ -0,0 +1,130 @@
+package forge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ci-status/internal/forge"
+)
+
+// countingClient counts posts and fails while err is set.
+type countingClient struct {
+	posts int
+	err   error
+}
+
+func (c *countingClient) SetStatus(context.Context, forge.StatusOpts) error {
+	c.posts++
+	return c.err
+}
+
+func TestDedupClientDropsRepeats(t *testing.T) {
+	inner := &countingClient{}
+	client := forge.NewDedupClient(inner)
+	running := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateRunning, Description: "Running..."}
+
+	for _, opts := range []forge.StatusOpts{
+		running,
+		running, // repeat: dropped
+		{Commit: "abc", Context: "test", State: forge.StateRunning, Description: "Running..."},
+		{Commit: "abc", Context: "lint", State: forge.StateRunning, Description: "Running...", TargetURL: "https://ci/1"},
+		{Commit: "abc", Context: "lint", State: forge.StateSuccess, Description: "Running...", TargetURL: "https://ci/1"},
+		{Commit: "abc", Context: "lint", State: forge.StateSuccess, Description: "Running...", TargetURL: "https://ci/1"}, // repeat
+	} {
+		if err := client.SetStatus(t.Context(), opts); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+	}
+	if inner.posts != 4 {
+		t.Fatalf("posts = %d, want 4", inner.posts)
+	}
+}
+
+func TestDedupClientRetriesFailedPosts(t *testing.T) {
+	inner := &countingClient{err: errors.New("502")}
+	client := forge.NewDedupClient(inner)
+	opts := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateSuccess}
+
+	if err := client.SetStatus(t.Context(), opts); err == nil {
+		t.Fatal("expected the inner error")
+	}
+	inner.err = nil
+	if err := client.SetStatus(t.Context(), opts); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if inner.posts != 2 {
+		t.Fatalf("posts = %d, want the failed status sent again", inner.posts)
+	}
+}
+
+func TestNewDedupClientKeepsNil(t *testing.T) {
+	if forge.NewDedupClient(nil) != nil {
+		t.Fatal("a nil client must stay nil")
+	}
+}
+
+// gatedClient blocks every post until release is closed, recording how
+// many are in flight at once.
+type gatedClient struct {
+	entered chan forge.StatusOpts
+	release chan struct{}
+
+	mu                  sync.Mutex
+	inFlight, maxFlight int
+	posts               []forge.StatusOpts
+}
+
+func (c *gatedClient) SetStatus(_ context.Context, opts forge.StatusOpts) error {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxFlight = max(c.maxFlight, c.inFlight)
+	c.mu.Unlock()
+	c.entered <- opts
+	<-c.release
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	c.posts = append(c.posts, opts)
+	return nil
+}
+
+func TestDedupClientSerializesPerContext(t *testing.T) {
+	inner := &gatedClient{entered: make(chan forge.StatusOpts, 2), release: make(chan struct{})}
+	client := forge.NewDedupClient(inner)
+	running := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateRunning}
+	done := running
+	done.State = forge.StateSuccess
+
+	var wg sync.WaitGroup
+	for _, opts := range []forge.StatusOpts{running, done} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = client.SetStatus(t.Context(), opts)
+		}()
+		if opts == running {
+			<-inner.entered
+		}
+	}
+	// The second post must wait for the first to finish.
+	select {
+	case opts := <-inner.entered:
+		t.Fatalf("%+v posted while another post for lint was in flight", opts)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(inner.release)
+	<-inner.entered
+	wg.Wait()
+
+	if inner.maxFlight != 1 || len(inner.posts) != 2 || inner.posts[1] != done {
+		t.Fatalf("max in flight %d, posts %+v", inner.maxFlight, inner.posts)
+	}
+	// The last status the forge received is the one remembered.
+	if err := client.SetStatus(t.Context(), done); err != nil || len(inner.posts) != 2 {
+		t.Fatalf("repeat of the final status was posted: %v, %+v", err, inner.posts)
+	}
+}
@@ -1,10 +1,15 @@
 package forge
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+
+	"ci-status/internal/credentials"
+	"ci-status/internal/gitdir"
 )
 
 // detectError is a stable detection sentinel. Prefer these (or fmt.Errorf %w
@@ -18,8 +23,9 @@ const (
 	ErrCouldNotLoadGitHubClient detectError = "could not load github client for url"
 	ErrUnsupportedForgeOverride detectError = "unsupported forge override"
 	ErrNoSupportedForge         detectError = "no supported forge detected for url"
-	ErrGitHubTokenNotSet        detectError = "GITHUB_TOKEN not set"
+	ErrNoToken                  detectError = "no forge token found"
 	ErrNoRemoteURL              detectError = "could not determine remote url for 'origin' or 'upstream'"
+	ErrUnknownRemote            detectError = "unknown git remote"
 )
 
 // DetectClient attempts to identify the appropriate ForgeClient by analyzing the repository's remote URL.
@@ -27,7 +33,7 @@ const (
 //
 // Behavior:
 //  1. Retrieves the 'origin' or 'upstream' remote URL.
-//  2. If 'overrideForge' is set (e.g. "github"), only that strategy is used; unknown
+//  2. If 'overrideForge' is set ("github" or "gitea"), only that strategy is used; unknown
 //     overrides error without falling through to auto-detect.
 //  3. Otherwise, it iterates through all registered strategies in precedence order.
 //  4. If a known forge remote is present but credentials are missing, returns a credentials error
@@ -41,7 +47,7 @@ func DetectClient(overrideForge string) (ForgeClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	return detectClientFromURL(originURL, overrideForge)
+	return detectClientFromURL(originURL, overrideForge, &credentials.Resolver{})
 }
 
 // detectClientFromURL selects a ForgeClient for a remote URL.
@@ -49,26 +55,37 @@ func DetectClient(overrideForge string) (ForgeClient, error) {
 //
 // When overrideForge is set, only that strategy is used (no auto-detect fallthrough).
 // Unknown overrides fail immediately so typos do not silently report to another forge.
-func detectClientFromURL(originURL, overrideForge string) (ForgeClient, error) {
+// Tokens are looked up through creds, which caches them across strategies.
+func detectClientFromURL(originURL, overrideForge string, creds *credentials.Resolver) (ForgeClient, error) {
 	if overrideForge != "" {
 		switch overrideForge {
 		case "github":
-			if client := LoadGitHub(originURL); client != nil {
+			if client := loadGitHub(originURL, creds); client != nil {
 				return client, nil
 			}
-			if err := missingCredentialsError(originURL); err != nil {
+			if err := missingCredentialsError(originURL, creds); err != nil {
 				return nil, err
 			}
 			return nil, fmt.Errorf("%w: %s", ErrCouldNotLoadGitHubClient, originURL)
+		case "gitea":
+			// The name run exports as CI_STATUS_FORGE for Gitea/Forgejo remotes,
+			// so nested ci-status calls (which read it as --forge) keep working.
+			if client := loadGeneric(originURL, creds); client != nil {
+				return client, nil
+			}
+			if err := missingCredentialsError(originURL, creds); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %s", ErrNoSupportedForge, originURL)
 		default:
-			return nil, fmt.Errorf("%w %q (supported: github)", ErrUnsupportedForgeOverride, overrideForge)
+			return nil, fmt.Errorf("%w %q (supported: github, gitea)", ErrUnsupportedForgeOverride, overrideForge)
 		}
 	}
 
 	// Auto-detect: try strategies in order of precedence.
 	strategies := []ForgeLoader{
-		LoadGitHub,
-		LoadGeneric,
+		func(url string) ForgeClient { return loadGitHub(url, creds) },
+		func(url string) ForgeClient { return loadGeneric(url, creds) },
 	}
 
 	for _, strategy := range strategies {
@@ -77,7 +94,7 @@ func detectClientFromURL(originURL, overrideForge string) (ForgeClient, error) {
 		}
 	}
 
-	if err := missingCredentialsError(originURL); err != nil {
+	if err := missingCredentialsError(originURL, creds); err != nil {
 		return nil, err
 	}
 
@@ -85,54 +102,104 @@ func detectClientFromURL(originURL, overrideForge string) (ForgeClient, error) {
 }
 
 // missingCredentialsError returns a clear error when the remote matches a known forge
-// but GITHUB_TOKEN is unset (loaders return nil for both "not this forge" and "no token").
-func missingCredentialsError(originURL string) error {
-	if os.Getenv("GITHUB_TOKEN") != "" {
+// but no token was found (loaders return nil for both "not this forge" and "no token").
+// The error lists every credential source tried.
+func missingCredentialsError(originURL string, creds *credentials.Resolver) error {
+	if owner, repo, err := ParseGitHubRemote(originURL); err == nil {
+		client := newGitHubAPIClient(owner, repo)
+		if _, err := creds.Lookup(tokenRequest(client, originURL)); err != nil {
+			return credentialsError(err, "GitHub remote detected")
+		}
 		return nil
 	}
 
-	if _, _, err := ParseGitHubRemote(originURL); err == nil {
-		return fmt.Errorf("%w (GitHub remote detected)", ErrGitHubTokenNotSet)
-	}
-
-	// Generic Gitea/Forgejo remotes also authenticate with GITHUB_TOKEN.
-	if _, _, err := ParseGenericRemote(originURL); err == nil {
-		host, _ := getHostAndScheme(originURL)
-		if host != "" && host != "github.com" && host != "api.github.com" {
-			return fmt.Errorf("%w (forge remote detected at %s)", ErrGitHubTokenNotSet, host)
+	if owner, repo, err := ParseGenericRemote(originURL); err == nil {
+		host, scheme := getHostAndScheme(originURL)
+		if host != "" && !isGitHubAPIHost(host) {
+			client := NewGitHubClient("", owner, repo)
+			client.BaseURL = fmt.Sprintf("%s://%s/api/v1", scheme, host)
+			if _, err := creds.Lookup(tokenRequest(client, originURL)); err != nil {
+				return credentialsError(err, "forge remote detected at "+host)
+			}
 		}
 	}
 
 	return nil
 }
 
+// credentialsError turns a failed token lookup into ErrNoToken naming the
+// sources tried; token file errors are returned as they are.
+func credentialsError(err error, detail string) error {
+	var notFound *credentials.NotFoundError
+	if !errors.As(err, &notFound) {
+		return err
+	}
+	return fmt.Errorf("%w (%s; tried %s)", ErrNoToken, detail, strings.Join(notFound.Tried, ", "))
+}
+
 // getOriginURL retrieves the remote URL for the repository.
 // It attempts to read from the 'origin' remote first, falling back to 'upstream' if 'origin' is not defined.
 // This supports forked repositories where the upstream might be the primary source of truth.
 func getOriginURL() (string, error) {
+	_, remoteURL, err := getRemote()
+	return remoteURL, err
+}
+
+// getRemote is getOriginURL that also reports which remote was used.
+func getRemote() (name, remoteURL string, err error) {
 	for _, remote := range []string{"origin", "upstream"} {
-		cmd := exec.Command("git", "remote", "get-url", remote)
-		out, err := cmd.Output()
-		if err == nil {
-			return strings.TrimSpace(string(out)), nil
+		if url, err := gitRemoteURL(remote); err == nil {
+			return remote, url, nil
 		}
 	}
 
-	return "", ErrNoRemoteURL
+	return "", "", ErrNoRemoteURL
+}
+
+// gitRemoteURL returns the URL of the named remote. The repository config is
+// read directly (package gitdir), so no git binary is needed; 'git remote
+// get-url' is only run when the repository cannot be read that way. A remote
+// the config does not define is not looked up again.
+func gitRemoteURL(name string) (string, error) {
+	if repo, err := gitdir.Open(""); err == nil {
+		url, err := repo.RemoteURL(name)
+		if err == nil || errors.Is(err, gitdir.ErrNoRemote) {
+			return url, err
+		}
+	}
+	out, err := exec.Command("git", "remote", "get-url", "--", name).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
 }
 
 // DetectCommit resolves the commit SHA to be reported.
 // It prioritizes the override value, then CI environment variables (GITHUB_SHA, CI_COMMIT_SHA, BITBUCKET_COMMIT),
-// and finally falls back to the current git HEAD.
+// and finally falls back to the current git HEAD, read from the repository
+// files when possible and from 'git rev-parse HEAD' otherwise.
 func DetectCommit(override string) (string, error) {
+	sha, _, err := detectCommit(override)
+	return sha, err
+}
+
+// detectCommit is DetectCommit that also names where the SHA came from
+// ("override", the environment variable, the HEAD file, or "git rev-parse HEAD").
+func detectCommit(override string) (sha, source string, err error) {
 	if override != "" {
-		return override, nil
+		return override, "override", nil
 	}
 
 	// CI Env vars
 	for _, env := range []string{"GITHUB_SHA", "CI_COMMIT_SHA", "BITBUCKET_COMMIT"} {
 		if sha := os.Getenv(env); sha != "" {
-			return sha, nil
+			return sha, env, nil
+		}
+	}
+
+	if repo, err := gitdir.Open(""); err == nil {
+		if sha, err := repo.Head(); err == nil {
+			return sha, filepath.Join(repo.GitDir, "HEAD"), nil
 		}
 	}
 
@@ -140,7 +207,7 @@ func DetectCommit(override string) (string, error) {
 	cmd := exec.Command("git", "rev-parse", "HEAD")
 	out, err := cmd.Output()
 	if err != nil {
-		return "", err
+		return "", "git rev-parse HEAD", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return strings.TrimSpace(string(out)), "git rev-parse HEAD", nil
 }
@@ -3,10 +3,13 @@ package forge
 import (
 	"strings"
 	"testing"
+
+	"ci-status/internal/credentials"
+	"ci-status/internal/credentials/credentialstest"
 )
 
 func TestDetectClientFromURL_MissingToken(t *testing.T) {
-	t.Setenv("GITHUB_TOKEN", "")
+	credentialstest.Isolate(t)
 
 	cases := []struct {
 		name    string
@@ -16,23 +19,23 @@ func TestDetectClientFromURL_MissingToken(t *testing.T) {
 		{
 			name:    "github https",
 			url:     "https://github.com/owner/repo.git",
-			wantSub: "GITHUB_TOKEN not set",
+			wantSub: "no forge token found",
 		},
 		{
 			name:    "github ssh",
 			url:     "git@github.com:owner/repo.git",
-			wantSub: "GITHUB_TOKEN not set",
+			wantSub: "no forge token found",
 		},
 		{
 			name:    "gitea https",
 			url:     "https://gitea.example.com/owner/repo.git",
-			wantSub: "GITHUB_TOKEN not set",
+			wantSub: "no forge token found",
 		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			client, err := detectClientFromURL(tt.url, "")
+			client, err := detectClientFromURL(tt.url, "", &credentials.Resolver{})
 			if client != nil {
 				t.Fatalf("expected nil client without token, got %#v", client)
 			}
@@ -52,7 +55,7 @@ func TestDetectClientFromURL_MissingToken(t *testing.T) {
 func TestDetectClientFromURL_UnsupportedRemote(t *testing.T) {
 	t.Setenv("GITHUB_TOKEN", "")
 
-	client, err := detectClientFromURL("not-a-remote", "")
+	client, err := detectClientFromURL("not-a-remote", "", &credentials.Resolver{})
 	if client != nil {
 		t.Fatalf("expected nil client, got %#v", client)
 	}
@@ -67,7 +70,7 @@ func TestDetectClientFromURL_UnsupportedRemote(t *testing.T) {
 func TestDetectClientFromURL_WithToken(t *testing.T) {
 	t.Setenv("GITHUB_TOKEN", "test-token")
 
-	client, err := detectClientFromURL("https://github.com/owner/repo.git", "")
+	client, err := detectClientFromURL("https://github.com/owner/repo.git", "", &credentials.Resolver{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -75,7 +78,7 @@ func TestDetectClientFromURL_WithToken(t *testing.T) {
 		t.Fatal("expected client with token and github remote")
 	}
 
-	generic, err := detectClientFromURL("https://gitea.example.com/owner/repo.git", "")
+	generic, err := detectClientFromURL("https://gitea.example.com/owner/repo.git", "", &credentials.Resolver{})
 	if err != nil {
 		t.Fatalf("unexpected error for generic: %v", err)
 	}
@@ -87,7 +90,7 @@ func TestDetectClientFromURL_WithToken(t *testing.T) {
 func TestDetectClientFromURL_OverrideGitHub(t *testing.T) {
 	t.Setenv("GITHUB_TOKEN", "test-token")
 
-	client, err := detectClientFromURL("https://github.com/owner/repo.git", "github")
+	client, err := detectClientFromURL("https://github.com/owner/repo.git", "github", &credentials.Resolver{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -100,7 +103,7 @@ func TestDetectClientFromURL_OverrideGitHubNoFallthrough(t *testing.T) {
 	t.Setenv("GITHUB_TOKEN", "test-token")
 
 	// Generic/Gitea remotes must not be used when the user forced --forge github.
-	client, err := detectClientFromURL("https://gitea.example.com/owner/repo.git", "github")
+	client, err := detectClientFromURL("https://gitea.example.com/owner/repo.git", "github", &credentials.Resolver{})
 	if client != nil {
 		t.Fatalf("expected nil client for non-GitHub remote with github override, got %#v", client)
 	}
@@ -115,7 +118,7 @@ func TestDetectClientFromURL_OverrideGitHubNoFallthrough(t *testing.T) {
 func TestDetectClientFromURL_UnsupportedOverride(t *testing.T) {
 	t.Setenv("GITHUB_TOKEN", "test-token")
 
-	client, err := detectClientFromURL("https://github.com/owner/repo.git", "gitlab")
+	client, err := detectClientFromURL("https://github.com/owner/repo.git", "gitlab", &credentials.Resolver{})
 	if client != nil {
 		t.Fatalf("expected nil client for unsupported override, got %#v", client)
 	}
@@ -128,16 +131,59 @@ func TestDetectClientFromURL_UnsupportedOverride(t *testing.T) {
 }
 
 func TestDetectClientFromURL_OverrideGitHubMissingToken(t *testing.T) {
-	t.Setenv("GITHUB_TOKEN", "")
+	credentialstest.Isolate(t)
 
-	client, err := detectClientFromURL("https://github.com/owner/repo.git", "github")
+	client, err := detectClientFromURL("https://github.com/owner/repo.git", "github", &credentials.Resolver{})
 	if client != nil {
 		t.Fatalf("expected nil client without token, got %#v", client)
 	}
 	if err == nil {
 		t.Fatal("expected credentials error")
 	}
-	if !strings.Contains(err.Error(), "GITHUB_TOKEN not set") {
+	if !strings.Contains(err.Error(), "no forge token found") {
 		t.Fatalf("want missing-token message, got %v", err)
 	}
 }
+
+func TestDetectClientFromURL_OverrideGitea(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	client, err := detectClientFromURL("https://gitea.example.com/owner/repo.git", "gitea", &credentials.Resolver{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name := Name(client); name != "gitea" {
+		t.Fatalf("Name = %q, want gitea", name)
+	}
+	if _, err := detectClientFromURL("https://github.com/owner/repo.git", "gitea", &credentials.Resolver{}); err == nil {
+		t.Fatal("expected an error for a GitHub remote with the gitea override")
+	}
+}
+
+func TestExplainLoaders(t *testing.T) {
+	credentialstest.Isolate(t)
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("GITHUB_API_URL", "")
+
+	cases := []struct {
+		name, url, token string
+		github, generic  string
+	}{
+		{"github remote", "git@github.com:owner/repo.git", "t", "matched github.com remote (owner/repo)", "rejected: GitHub hosts"},
+		{"gitea remote", "https://gitea.example.com/owner/repo.git", "t", "rejected: remote is not github.com", "matched Gitea/Forgejo remote (owner/repo at https://gitea.example.com/api/v1)"},
+		{"no token", "https://gitea.example.com/owner/repo.git", "", "rejected: remote is not github.com", "rejected: no forge token found (gitea; tried $GITEA_TOKEN, $GITHUB_TOKEN"},
+		{"no github token", "https://github.com/owner/repo.git", "", "rejected: no forge token found (github; tried $GITHUB_TOKEN, $GH_TOKEN,", "rejected: GitHub hosts"},
+		{"bad remote", "not-a-remote", "t", "rejected: remote is not github.com", "rejected: "},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", tt.token)
+			if got := explainGitHub(tt.url, &credentials.Resolver{}).Reason; !strings.HasPrefix(got, tt.github) {
+				t.Errorf("github: %q, want prefix %q", got, tt.github)
+			}
+			if got := explainGeneric(tt.url, &credentials.Resolver{}).Reason; !strings.HasPrefix(got, tt.generic) {
+				t.Errorf("generic: %q, want prefix %q", got, tt.generic)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ci-status/internal/credentials"
+)
+
+// LoaderResult explains one detection strategy's decision.
+type LoaderResult struct {
+	// Name is the strategy ("github", "generic").
+	Name string
+	// Selected is true for the strategy whose client DetectClient returns.
+	Selected bool
+	// Reason says why the strategy matched or was rejected.
+	Reason string
+}
+
+// Diagnosis records every decision DetectClient and DetectCommit make, for
+// 'ci-status doctor'. Errors are kept instead of returned so one failing step
+// does not hide the others.
+type Diagnosis struct {
+	// Remote is the git remote used ("origin" or "upstream", or the one
+	// the sink names).
+	Remote    string
+	RemoteURL string
+	RemoteErr error
+
+	Loaders   []LoaderResult
+	Client    ForgeClient
+	ClientErr error
+	// Owner, Repo and APIBaseURL describe the selected client.
+	Owner      string
+	Repo       string
+	APIBaseURL string
+	// TokenSource names where the token was found (see credentials.Token);
+	// empty if none.
+	TokenSource string
+	// MaskedToken shows only enough of the token to recognise it.
+	MaskedToken string
+
+	Commit       string
+	CommitSource string
+	CommitErr    error
+}
+
+// Diagnose runs forge and commit detection like DetectSink and
+// DetectCommit, recording why each strategy was chosen or rejected.
+func Diagnose(sink Sink, overrideCommit string) Diagnosis {
+	var d Diagnosis
+	d.Commit, d.CommitSource, d.CommitErr = detectCommit(overrideCommit)
+
+	if sink.Remote != "" {
+		d.Remote = sink.Remote
+		d.RemoteURL, d.RemoteErr = resolveRemote(sink.Remote)
+	} else {
+		d.Remote, d.RemoteURL, d.RemoteErr = getRemote()
+	}
+	overrideForge := sink.Forge
+	creds, err := sink.resolver()
+	if err != nil {
+		d.ClientErr = err
+		return d
+	}
+
+	switch {
+	case sink.Repo != "":
+		// The remote is not parsed at all (it may not even exist).
+		d.Client, d.ClientErr = DetectSink(sink)
+		d.Loaders = []LoaderResult{
+			{Name: "github", Reason: "skipped: --repo " + sink.Repo},
+			{Name: "generic", Reason: "skipped: --repo " + sink.Repo},
+		}
+	case d.RemoteErr != nil:
+		return d
+	default:
+		d.Loaders = []LoaderResult{explainGitHub(d.RemoteURL, creds), explainGeneric(d.RemoteURL, creds)}
+		d.Client, d.ClientErr = detectClientFromURL(d.RemoteURL, overrideForge, creds)
+		if overrideForge != "" {
+			for i := range d.Loaders {
+				if !strings.EqualFold(d.Loaders[i].Name, overrideForge) && !(overrideForge == "gitea" && d.Loaders[i].Name == "generic") {
+					d.Loaders[i].Reason = "skipped: --forge " + overrideForge
+				}
+			}
+		}
+		if d.ClientErr == nil && sink.APIURL != "" {
+			d.Client, d.ClientErr = DetectSink(sink)
+		}
+	}
+	if gh, ok := d.Client.(*GitHubClient); ok {
+		for i := range d.Loaders {
+			d.Loaders[i].Selected = d.Loaders[i].Name == loaderName(gh)
+		}
+		d.Owner, d.Repo = gh.Owner, gh.Repo
+		d.APIBaseURL = gh.apiBaseURL()
+		d.TokenSource = gh.TokenSource
+		d.MaskedToken = MaskToken(gh.Token)
+		if gh.Installation != nil {
+			d.MaskedToken = "(installation token)"
+		}
+	}
+	return d
+}
+
+// loaderName maps a client back to the strategy that built it.
+func loaderName(c *GitHubClient) string {
+	if c.ForgeName() == "gitea" {
+		return "generic"
+	}
+	return "github"
+}
+
+// explainGitHub mirrors LoadGitHub's checks in order.
+func explainGitHub(remoteURL string, creds *credentials.Resolver) LoaderResult {
+	r := LoaderResult{Name: "github"}
+	var matched string
+	if owner, repo, err := ParseGitHubRemote(remoteURL); err == nil {
+		matched = fmt.Sprintf("matched github.com remote (%s/%s)", owner, repo)
+	} else if !githubActionsEnvPresent() {
+		r.Reason = "rejected: remote is not github.com and GITHUB_ACTIONS/GITHUB_API_URL are not set"
+		return r
+	} else if owner, repo, ok := parseGitHubRepositoryEnv(); ok {
+		matched = fmt.Sprintf("matched GITHUB_REPOSITORY (%s/%s)", owner, repo)
+	} else {
+		r.Reason = "rejected: GitHub Actions environment without a valid GITHUB_REPOSITORY"
+		return r
+	}
+	owner, repo, _ := resolveGitHubOwnerRepo(remoteURL)
+	if _, err := creds.Lookup(tokenRequest(newGitHubAPIClient(owner, repo), remoteURL)); err != nil {
+		r.Reason = "rejected: " + credentialsError(err, "github").Error()
+		return r
+	}
+	r.Reason = matched
+	return r
+}
+
+// explainGeneric mirrors LoadGeneric's checks in order.
+func explainGeneric(remoteURL string, creds *credentials.Resolver) LoaderResult {
+	r := LoaderResult{Name: "generic"}
+	owner, repo, err := ParseGenericRemote(remoteURL)
+	if err != nil {
+		r.Reason = "rejected: " + err.Error()
+		return r
+	}
+	host, scheme := getHostAndScheme(remoteURL)
+	switch {
+	case host == "":
+		r.Reason = "rejected: cannot determine API host from remote"
+		return r
+	case isGitHubAPIHost(host):
+		r.Reason = "rejected: GitHub hosts are handled by the github loader"
+		return r
+	}
+	client := NewGitHubClient("", owner, repo)
+	client.BaseURL = fmt.Sprintf("%s://%s/api/v1", scheme, host)
+	if _, err := creds.Lookup(tokenRequest(client, remoteURL)); err != nil {
+		r.Reason = "rejected: " + credentialsError(err, "gitea").Error()
+		return r
+	}
+	r.Reason = fmt.Sprintf("matched Gitea/Forgejo remote (%s/%s at %s)", owner, repo, client.BaseURL)
+	return r
+}
+
+// MaskToken hides all but a recognisable prefix of a token
+// ("ghp_…(40 chars)"). Short tokens are fully masked.
+func MaskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) < 12 {
+		return fmt.Sprintf("****(%d chars)", len(token))
+	}
+	return fmt.Sprintf("%s…(%d chars)", token[:4], len(token))
+}
+
+// AccessCheck is the result of a read-only repository lookup.
+type AccessCheck struct {
+	// URL is the endpoint queried.
+	URL string
+	// Status is the HTTP status line ("200 OK").
+	Status string
+	// Push reports the token's push permission when the API returns it
+	// (commit statuses need push access); nil when not reported.
+	Push *bool
+	// Scopes is the classic token's X-OAuth-Scopes header, if any.
+	Scopes string
+}
+
+// CheckAccess fetches the repository (GET /repos/{owner}/{repo}) to validate
+// the token without changing anything. A non-2xx status is returned as part
+// of the check, not as an error; errors mean the request itself failed.
+func (c *GitHubClient) CheckAccess(ctx context.Context) (AccessCheck, error) {
+	check := AccessCheck{URL: fmt.Sprintf("%s/repos/%s/%s", c.apiBaseURL(), c.Owner, c.Repo)}
+	req, err := http.NewRequestWithContext(ctx, "GET", check.URL, nil)
+	if err != nil {
+		return check, fmt.Errorf("create request: %w", err)
+	}
+	// Sanitize token to prevent header injection vulnerabilities.
+	sanitizedToken := strings.NewReplacer("\n", "", "\r", "").Replace(c.Token)
+	if sanitizedToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", sanitizedToken))
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if err := c.authorize(ctx, req); err != nil {
+		return check, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return check, fmt.Errorf("execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	check.Status = resp.Status
+	check.Scopes = resp.Header.Get("X-OAuth-Scopes")
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var body struct {
+			Permissions *struct {
+				Push bool `json:"push"`
+			} `json:"permissions"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Permissions != nil {
+			check.Push = &body.Permissions.Push
+		}
+	}
+	return check, nil
+}
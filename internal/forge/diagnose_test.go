@@ -0,0 +1,74 @@
+package forge_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ci-status/internal/forge"
+)
+
+func TestMaskToken(t *testing.T) {
+	cases := map[string]string{
+		"":                     "",
+		"short":                "****(5 chars)",
+		"ghp_0123456789abcdef": "ghp_…(20 chars)",
+	}
+	for token, want := range cases {
+		if got := forge.MaskToken(token); got != want {
+			t.Errorf("MaskToken(%q) = %q, want %q", token, got, want)
+		}
+		if len(token) >= 12 && strings.Contains(forge.MaskToken(token), token[4:]) {
+			t.Errorf("MaskToken(%q) leaks the secret part", token)
+		}
+	}
+}
+
+func TestCheckAccessReadsPermissions(t *testing.T) {
+	var gotMethod, gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotAuth = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		w.Header().Set("X-OAuth-Scopes", "repo:status")
+		_, _ = w.Write([]byte(`{"full_name":"owner/repo","permissions":{"admin":false,"push":true,"pull":true}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := forge.NewGitHubClient("token\n", "owner", "repo")
+	client.BaseURL = srv.URL
+
+	check, err := client.CheckAccess(t.Context())
+	if err != nil {
+		t.Fatalf("CheckAccess: %v", err)
+	}
+	if gotMethod != http.MethodGet || gotPath != "/repos/owner/repo" {
+		t.Fatalf("request = %s %s, want GET /repos/owner/repo", gotMethod, gotPath)
+	}
+	if gotAuth != "Bearer token" {
+		t.Fatalf("Authorization = %q, want sanitized token", gotAuth)
+	}
+	if check.Status != "200 OK" || check.Scopes != "repo:status" {
+		t.Fatalf("check = %+v", check)
+	}
+	if check.Push == nil || !*check.Push {
+		t.Fatalf("Push = %v, want true", check.Push)
+	}
+}
+
+func TestCheckAccessReportsHTTPErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := forge.NewGitHubClient("bad", "owner", "repo")
+	client.BaseURL = srv.URL
+
+	check, err := client.CheckAccess(t.Context())
+	if err != nil {
+		t.Fatalf("CheckAccess: %v", err)
+	}
+	if !strings.HasPrefix(check.Status, "401") || check.Push != nil {
+		t.Fatalf("check = %+v, want 401 without permissions", check)
+	}
+}
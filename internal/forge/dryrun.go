@@ -0,0 +1,76 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// StatusRequester is implemented by clients that can build the HTTP request
+// SetStatus would send, so DryRunClient can print it instead.
+type StatusRequester interface {
+	StatusRequest(ctx context.Context, opts StatusOpts) (*http.Request, error)
+}
+
+// DryRunClient wraps the detected client and prints the status requests it
+// would send to Out instead of sending them. Client may be nil (no forge
+// detected); the status options are printed instead of a request.
+type DryRunClient struct {
+	Client ForgeClient
+	Out    io.Writer
+
+	mu sync.Mutex
+}
+
+// NewDryRunClient wraps client so statuses are printed to out.
+func NewDryRunClient(client ForgeClient, out io.Writer) *DryRunClient {
+	return &DryRunClient{Client: client, Out: out}
+}
+
+// SetStatus prints the method, URL, Authorization header (token masked) and
+// JSON body of the request the wrapped client would send.
+func (c *DryRunClient) SetStatus(ctx context.Context, opts StatusOpts) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.Client.(StatusRequester)
+	if !ok {
+		body, err := json.Marshal(map[string]string{
+			"state":       string(opts.State),
+			"description": opts.Description,
+			"context":     opts.Context,
+			"target_url":  opts.TargetURL,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal request body: %w", err)
+		}
+		_, err = fmt.Fprintf(c.Out, "[dry-run] %s (commit %s, forge not detected)\n%s\n", opts.State, opts.Commit, body)
+		return err
+	}
+
+	req, err := r.StatusRequest(ctx, opts)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("read request body: %w", err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[dry-run] %s %s\n", req.Method, req.URL)
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		fmt.Fprintf(&b, "Authorization: Bearer %s\n", MaskToken(strings.TrimPrefix(auth, "Bearer ")))
+	}
+	fmt.Fprintf(&b, "%s\n", body)
+	_, err = io.WriteString(c.Out, b.String())
+	return err
+}
+
+// ForgeName implements Namer for the wrapped client.
+func (c *DryRunClient) ForgeName() string {
+	return Name(c.Client)
+}
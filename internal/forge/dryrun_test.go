@@ -0,0 +1,53 @@
+package forge_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"ci-status/internal/forge"
+)
+
+func TestDryRunClientPrintsRequest(t *testing.T) {
+	gh := forge.NewGitHubClient("ghp_0123456789abcdef", "owner", "repo")
+	gh.BaseURL = "https://gitea.example.com/api/v1/"
+	var out bytes.Buffer
+	client := forge.NewDryRunClient(gh, &out)
+
+	err := client.SetStatus(t.Context(), forge.StatusOpts{
+		Commit:      "abc123",
+		Context:     "lint",
+		State:       forge.StateRunning,
+		Description: "Running...",
+		TargetURL:   "https://ci.example.com/1",
+	})
+	if err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+
+	want := "[dry-run] POST https://gitea.example.com/api/v1/repos/owner/repo/statuses/abc123\n" +
+		"Authorization: Bearer ghp_…(20 chars)\n" +
+		`{"context":"lint","description":"Running...","state":"pending","target_url":"https://ci.example.com/1"}` + "\n"
+	if out.String() != want {
+		t.Fatalf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if forge.Name(client) != "gitea" {
+		t.Fatalf("Name = %q, want the wrapped client's name", forge.Name(client))
+	}
+}
+
+func TestDryRunClientWithoutForge(t *testing.T) {
+	var out bytes.Buffer
+	client := forge.NewDryRunClient(nil, &out)
+
+	err := client.SetStatus(t.Context(), forge.StatusOpts{Commit: "abc123", Context: "lint", State: forge.StateSuccess})
+	if err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if !strings.Contains(out.String(), "forge not detected") || !strings.Contains(out.String(), `"context":"lint"`) {
+		t.Fatalf("output = %q", out.String())
+	}
+	if forge.Name(client) != "" {
+		t.Fatalf("Name = %q, want empty", forge.Name(client))
+	}
+}
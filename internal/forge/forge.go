@@ -129,6 +129,21 @@ type ForgeClient interface {
 	SetStatus(ctx context.Context, opts StatusOpts) error
 }
 
+// Namer is implemented by clients that can name their forge. Decorating
+// clients should forward it so Name sees through them.
+type Namer interface {
+	ForgeName() string
+}
+
+// Name returns a short identifier of the forge behind client ("github",
+// "gitea"), or "" for nil clients and clients that do not implement Namer.
+func Name(client ForgeClient) string {
+	if n, ok := client.(Namer); ok {
+		return n.ForgeName()
+	}
+	return ""
+}
+
 // ForgeLoader is a strategy function that attempts to instantiate a ForgeClient from a remote URL.
 // It returns nil if the URL is not supported by this strategy, allowing the next strategy to be tried.
 type ForgeLoader func(url string) ForgeClient
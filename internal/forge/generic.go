@@ -4,8 +4,9 @@ import (
 	"fmt"
 	"net"
 	"net/url"
-	"os"
 	"strings"
+
+	"ci-status/internal/credentials"
 )
 
 // genericError is a stable generic-remote parse sentinel. Prefer these (or
@@ -23,6 +24,12 @@ const (
 // It assumes the forge supports a GitHub-compatible API at `/api/v1`.
 // It explicitly rejects GitHub URLs to prevent fallback loops or incorrect client initialization.
 func LoadGeneric(remoteURL string) ForgeClient {
+	return loadGeneric(remoteURL, &credentials.Resolver{})
+}
+
+// loadGeneric is LoadGeneric with tokens looked up through creds
+// (GITEA_TOKEN, then GITHUB_TOKEN as before; see credentials.EnvVars).
+func loadGeneric(remoteURL string, creds *credentials.Resolver) ForgeClient {
 	owner, repo, err := ParseGenericRemote(remoteURL)
 	if err != nil {
 		return nil
@@ -41,15 +48,14 @@ func LoadGeneric(remoteURL string) ForgeClient {
 		return nil
 	}
 
-	// Use GITHUB_TOKEN as fallback for generic forge token
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return nil
-	}
-
-	client := NewGitHubClient(token, owner, repo)
+	client := NewGitHubClient("", owner, repo)
 	client.BaseURL = fmt.Sprintf("%s://%s/api/v1", scheme, host)
 
+	token, err := creds.Lookup(tokenRequest(client, remoteURL))
+	if err != nil {
+		return nil
+	}
+	client.setToken(token)
 	return client
 }
 
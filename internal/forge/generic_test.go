@@ -172,6 +172,9 @@ func TestLoadGenericBaseURL(t *testing.T) {
 			if gh.Owner != "owner" || gh.Repo != "repo" {
 				t.Fatalf("owner/repo = %s/%s, want owner/repo", gh.Owner, gh.Repo)
 			}
+			if name := forge.Name(client); name != "gitea" {
+				t.Fatalf("Name = %q, want gitea", name)
+			}
 		})
 	}
 }
@@ -182,3 +185,12 @@ func TestLoadGenericRequiresToken(t *testing.T) {
 		t.Fatalf("expected nil without token, got %#v", client)
 	}
 }
+
+func TestNameGitHub(t *testing.T) {
+	if name := forge.Name(forge.NewGitHubClient("t", "o", "r")); name != "github" {
+		t.Fatalf("Name = %q, want github", name)
+	}
+	if name := forge.Name(nil); name != "" {
+		t.Fatalf("Name(nil) = %q, want empty", name)
+	}
+}
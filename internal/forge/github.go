@@ -11,6 +11,9 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"ci-status/internal/credentials"
+	"ci-status/internal/githubapp"
 )
 
 // githubError is a stable GitHub client / remote-parse sentinel. Prefer these
@@ -34,6 +37,13 @@ type GitHubClient struct {
 	Owner   string
 	Repo    string
 	BaseURL string
+	// TokenSource names where Token was found (see credentials.Token),
+	// for doctor; empty when the token was passed in directly.
+	TokenSource string
+	// Installation, when set, authenticates as a GitHub App instead of
+	// Token. Its tokens are minted when a request is sent, so dry runs
+	// (StatusRequest) never contact the app endpoints.
+	Installation *githubapp.Installation
 }
 
 // NewGitHubClient creates a new instance of GitHubClient.
@@ -68,18 +78,51 @@ func truncateRunes(s string, max int) string {
 	return string(runes[:max-1]) + "…"
 }
 
+// ForgeName implements Namer. Gitea/Forgejo clients share this type and are
+// recognized by the /api/v1 base URL LoadGeneric gives them.
+func (c *GitHubClient) ForgeName() string {
+	if strings.HasSuffix(strings.TrimSuffix(c.BaseURL, "/"), "/api/v1") {
+		return "gitea"
+	}
+	return "github"
+}
+
 // SetStatus updates the commit status on GitHub and GitHub-compatible APIs
 // (GitHub Enterprise, Gitea/Forgejo via /api/v1). Commit status endpoints only
 // accept error|failure|pending|success, so StateRunning is always mapped to pending.
 // Description is capped at 140 characters and context at 100 (GitHub API limits).
+// Rate-limit rejections are returned as *RateLimitError.
 func (c *GitHubClient) SetStatus(ctx context.Context, opts StatusOpts) error {
-	baseURL := c.BaseURL
-	if baseURL == "" {
-		baseURL = "https://api.github.com"
+	req, err := c.StatusRequest(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := c.authorize(ctx, req); err != nil {
+		return err
+	}
+
+	// Use a custom client with timeout to prevent hanging requests.
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return rateLimited(resp, fmt.Errorf("%w: %s - %s", ErrGitHubAPIError, resp.Status, string(respBody)))
 	}
-	baseURL = strings.TrimSuffix(baseURL, "/")
 
-	statusURL := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", baseURL, c.Owner, c.Repo, opts.Commit)
+	return nil
+}
+
+// StatusRequest implements StatusRequester: it builds the request SetStatus
+// sends for opts, headers included.
+func (c *GitHubClient) StatusRequest(ctx context.Context, opts StatusOpts) (*http.Request, error) {
+	statusURL := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", c.apiBaseURL(), c.Owner, c.Repo, opts.Commit)
 
 	state := string(opts.State)
 	if opts.State == StateRunning {
@@ -97,12 +140,12 @@ func (c *GitHubClient) SetStatus(ctx context.Context, opts StatusOpts) error {
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("marshal request body: %w", err)
+		return nil, fmt.Errorf("marshal request body: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", statusURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 
 	// Sanitize token to prevent header injection vulnerabilities.
@@ -112,23 +155,35 @@ func (c *GitHubClient) SetStatus(ctx context.Context, opts StatusOpts) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	return req, nil
+}
 
-	// Use a custom client with timeout to prevent hanging requests.
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+// authorize sets the Authorization header to a current installation token
+// when the client authenticates as a GitHub App.
+func (c *GitHubClient) authorize(ctx context.Context, req *http.Request) error {
+	if c.Installation == nil {
+		return nil
+	}
+	token, err := c.Installation.Token(ctx)
 	if err != nil {
-		return fmt.Errorf("execute request: %w", err)
+		return fmt.Errorf("github app %s: %w", c.Installation.AppID(), err)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+	// Sanitize token to prevent header injection vulnerabilities.
+	req.Header.Set("Authorization", "Bearer "+strings.NewReplacer("\n", "", "\r", "").Replace(token))
+	return nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("%w: %s - %s", ErrGitHubAPIError, resp.Status, string(respBody))
-	}
+// setToken stores a token found by a credentials.Resolver.
+func (c *GitHubClient) setToken(token credentials.Token) {
+	c.Token, c.TokenSource, c.Installation = token.Value, token.Source, token.Installation
+}
 
-	return nil
+// apiBaseURL is BaseURL with the api.github.com default applied.
+func (c *GitHubClient) apiBaseURL() string {
+	if c.BaseURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
 }
 
 // LoadGitHub is a strategy to initialize a GitHubClient for GitHub.com and GitHub
@@ -143,27 +198,60 @@ func (c *GitHubClient) SetStatus(ctx context.Context, opts StatusOpts) error {
 // https://api.github.com or https://ghe.example/api/v3). Without it, the client
 // defaults to api.github.com.
 //
-// Requires GITHUB_TOKEN. Returns nil when the remote is not GitHub and no
+// Requires a token (GITHUB_TOKEN, GH_TOKEN, netrc or git credentials; see
+// package credentials). Returns nil when the remote is not GitHub and no
 // Actions/GHES identity is available (so LoadGeneric can handle Gitea/Forgejo).
 func LoadGitHub(remoteURL string) ForgeClient {
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		// If the token is missing, we cannot interact with the API, so we return nil.
-		return nil
-	}
+	return loadGitHub(remoteURL, &credentials.Resolver{})
+}
 
+// loadGitHub is LoadGitHub with tokens looked up through creds. The remote
+// is checked first so credential helpers only run for GitHub remotes.
+func loadGitHub(remoteURL string, creds *credentials.Resolver) ForgeClient {
 	owner, repo, ok := resolveGitHubOwnerRepo(remoteURL)
 	if !ok {
 		return nil
 	}
 
-	client := NewGitHubClient(token, owner, repo)
+	client := newGitHubAPIClient(owner, repo)
+	token, err := creds.Lookup(tokenRequest(client, remoteURL))
+	if err != nil {
+		// Without a token, we cannot interact with the API, so we return nil.
+		return nil
+	}
+	client.setToken(token)
+	return client
+}
+
+// newGitHubAPIClient returns a client without token for owner/repo at
+// GITHUB_API_URL, or api.github.com when unset.
+func newGitHubAPIClient(owner, repo string) *GitHubClient {
+	client := NewGitHubClient("", owner, repo)
 	if apiURL := strings.TrimSpace(os.Getenv("GITHUB_API_URL")); apiURL != "" {
 		client.BaseURL = strings.TrimSuffix(apiURL, "/")
 	}
 	return client
 }
 
+// tokenRequest describes the token c needs: its forge and repository, its
+// API host (for netrc) and the remote host (for git credential helpers). Without a
+// remote, the API host stands in for it, github.com for api.github.com.
+func tokenRequest(c *GitHubClient, remoteURL string) credentials.Request {
+	req := credentials.Request{Forge: c.ForgeName(), APIURL: c.apiBaseURL(), Owner: c.Owner, Repo: c.Repo}
+	api, err := url.Parse(c.apiBaseURL())
+	if err == nil {
+		req.APIHost = api.Host
+	}
+	req.Host, req.Protocol = getHostAndScheme(remoteURL)
+	if req.Host == "" && err == nil {
+		req.Host, req.Protocol = api.Host, api.Scheme
+		if isGitHubAPIHost(api.Host) {
+			req.Host = "github.com"
+		}
+	}
+	return req
+}
+
 // resolveGitHubOwnerRepo picks owner/repo for a GitHub client.
 // github.com remotes always win; Actions/GHES env is only used when the remote
 // is not github.com so we do not steal Gitea/Forgejo remotes.
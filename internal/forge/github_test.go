@@ -1,10 +1,16 @@
 package forge_test
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -258,3 +264,58 @@ func TestLoadGitHub_MissingToken(t *testing.T) {
 		t.Fatalf("expected nil without token, got %#v", c)
 	}
 }
+
+// TestDetectSinkGitHubApp posts a status with an installation token minted
+// for the detected repository, without any other token configured.
+func TestDetectSinkGitHubApp(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_ACTIONS", "")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	var statusAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/repos/acme/app/installation":
+			_, _ = io.WriteString(w, `{"id": 7}`)
+		case "/app/installations/7/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"token": "ghs_installation", "expires_at": "2999-01-01T00:00:00Z"}`)
+		default:
+			statusAuth = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("GITHUB_API_URL", srv.URL)
+
+	client, err := forge.DetectSink(forge.Sink{Remote: "https://github.com/acme/app.git", AppID: "123", AppKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("DetectSink: %v", err)
+	}
+	gh := asGitHubClient(t, client)
+	if gh.Token != "" || gh.TokenSource != "GitHub App 123" {
+		t.Fatalf("token %q from %q, want GitHub App 123", gh.Token, gh.TokenSource)
+	}
+
+	opts := forge.StatusOpts{Commit: "abc123", Context: "lint", State: forge.StateSuccess}
+	if _, err := gh.StatusRequest(t.Context(), opts); err != nil || len(requests) != 0 {
+		t.Fatalf("StatusRequest: %v, requests %q; dry runs must not mint tokens", err, requests)
+	}
+	if err := gh.SetStatus(t.Context(), opts); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if statusAuth != "Bearer ghs_installation" {
+		t.Fatalf("status Authorization = %q, requests %q", statusAuth, requests)
+	}
+}
@@ -0,0 +1,245 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"ci-status/internal/credentials"
+	"ci-status/internal/githubapp"
+)
+
+// Sink is one forge to report to: a forge override ("github", "gitea", or
+// empty to auto-detect) and the git remote it is detected from.
+type Sink struct {
+	Forge string
+	// Remote is a git remote name ("github") or URL; empty uses
+	// origin/upstream like DetectClient.
+	Remote string
+	// Repo ("owner/name") and APIURL replace what is parsed from the
+	// remote (--repo, --api-url), e.g. for a deploy job in one repository
+	// reporting to another.
+	Repo   string
+	APIURL string
+	// TokenFile is read for the token before any other source
+	// (--token-file, see credentials.Resolver).
+	TokenFile string
+	// AppID, AppKeyFile and InstallationID authenticate GitHub requests as
+	// a GitHub App (--github-app-id, --github-app-key-file,
+	// --github-app-installation-id; see package githubapp).
+	AppID          string
+	AppKeyFile     string
+	InstallationID int64
+}
+
+// ParseSink parses a --forge value: "github", "gitea", or "forge=remote",
+// where remote is a git remote name or URL. This lets a repository mirrored
+// from Gitea to GitHub report to both through their own remotes.
+func ParseSink(spec string) Sink {
+	name, remote, _ := strings.Cut(spec, "=")
+	return Sink{Forge: strings.TrimSpace(name), Remote: strings.TrimSpace(remote)}
+}
+
+// For parses a --forge value (see ParseSink) and fills in the overrides
+// of base: base.Remote unless the value names its own remote, and always
+// Repo, APIURL and the credentials.
+func (base Sink) For(spec string) Sink {
+	s := ParseSink(spec)
+	if s.Remote == "" {
+		s.Remote = base.Remote
+	}
+	s.Repo, s.APIURL, s.TokenFile = base.Repo, base.APIURL, base.TokenFile
+	s.AppID, s.AppKeyFile, s.InstallationID = base.AppID, base.AppKeyFile, base.InstallationID
+	return s
+}
+
+// DetectSink builds the client for one sink (see detectClientFromURL).
+// With Repo set the client is built directly (see clientForRepo); APIURL
+// alone only replaces the detected client's API base URL.
+func DetectSink(s Sink) (ForgeClient, error) {
+	creds, err := s.resolver()
+	if err != nil {
+		return nil, err
+	}
+	if s.Repo != "" {
+		return clientForRepo(s, creds)
+	}
+	var apiURL string
+	if s.APIURL != "" {
+		if apiURL, err = parseAPIURL(s.APIURL); err != nil {
+			return nil, err
+		}
+	}
+
+	var remoteURL string
+	if s.Remote == "" {
+		remoteURL, err = getOriginURL()
+	} else {
+		remoteURL, err = resolveRemote(s.Remote)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client, err := detectClientFromURL(remoteURL, s.Forge, creds)
+	if err != nil {
+		return nil, err
+	}
+	if gh, ok := client.(*GitHubClient); ok && apiURL != "" {
+		// The token was looked up for the remote's API host; look it up
+		// again for the one the statuses are sent to, as clientForRepo does.
+		gh.BaseURL = apiURL
+		token, err := creds.Lookup(tokenRequest(gh, remoteURL))
+		if err != nil {
+			return nil, credentialsError(err, "--api-url "+apiURL)
+		}
+		gh.setToken(token)
+	}
+	return client, nil
+}
+
+// resolver returns the token lookup for the sink's credentials.
+func (s Sink) resolver() (*credentials.Resolver, error) {
+	app, err := githubapp.Load(s.AppID, s.AppKeyFile, s.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+	return &credentials.Resolver{TokenFile: s.TokenFile, GitHubApp: app}, nil
+}
+
+// resolveRemote returns remote unchanged when it already is a URL, and the
+// URL of the git remote with that name otherwise.
+func resolveRemote(remote string) (string, error) {
+	if strings.ContainsAny(remote, ":/@") {
+		return remote, nil
+	}
+	url, err := gitRemoteURL(remote)
+	if err != nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownRemote, remote)
+	}
+	return url, nil
+}
+
+// DetectClients builds a client for every --forge value. base holds the
+// --remote, --repo and --api-url overrides; they apply to every value, and
+// base.Remote only to values naming no remote of their own.
+//
+// Without values, or with a single one naming no remote, the result is the
+// sink's plain client. Otherwise it is a *MultiClient over the sinks that
+// were detected (so its ForgeName keeps the remotes); err then reports the
+// sinks that were not, and a non-nil client may come with a non-nil err.
+func DetectClients(specs []string, base Sink, requireAll bool) (ForgeClient, error) {
+	switch {
+	case len(specs) == 0:
+		return DetectSink(base)
+	case len(specs) == 1 && ParseSink(specs[0]).Remote == "":
+		return DetectSink(base.For(specs[0]))
+	}
+
+	m := &MultiClient{RequireAll: requireAll}
+	var errs []error
+	for _, spec := range specs {
+		client, err := DetectSink(base.For(spec))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("--forge %s: %w", spec, err))
+			continue
+		}
+		m.Clients = append(m.Clients, client)
+		m.Specs = append(m.Specs, spec)
+	}
+	if len(m.Clients) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return m, errors.Join(errs...)
+}
+
+// MultiClient posts every status to several forges concurrently.
+type MultiClient struct {
+	Clients []ForgeClient
+	// Specs optionally holds the --forge value each client was detected
+	// from; it names the sinks in errors and in ForgeName.
+	Specs []string
+	// RequireAll makes SetStatus fail when any sink fails. Otherwise it
+	// fails only when every sink failed, and partial failures go to
+	// OnPartialError so they can still be shown as warnings.
+	RequireAll     bool
+	OnPartialError func(err error)
+}
+
+// SetStatus posts opts to every sink and waits for all of them. Errors are
+// prefixed with the sink's forge name.
+func (m *MultiClient) SetStatus(ctx context.Context, opts StatusOpts) error {
+	errs := make([]error, len(m.Clients))
+	var wg sync.WaitGroup
+	for i, client := range m.Clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.SetStatus(ctx, opts); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", m.label(i), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	err := errors.Join(errs...)
+	switch {
+	case failed == 0:
+		return nil
+	case m.RequireAll || failed == len(m.Clients):
+		return err
+	default:
+		if m.OnPartialError != nil {
+			m.OnPartialError(err)
+		}
+		return nil
+	}
+}
+
+// label names sink i in errors: its --forge value, or its forge name
+// numbered when several sinks share it.
+func (m *MultiClient) label(i int) string {
+	if i < len(m.Specs) && m.Specs[i] != "" {
+		return m.Specs[i]
+	}
+	name := Name(m.Clients[i])
+	if name == "" {
+		name = "forge"
+	}
+	same := 0
+	for _, c := range m.Clients {
+		if Name(c) == Name(m.Clients[i]) {
+			same++
+		}
+	}
+	if same > 1 {
+		return fmt.Sprintf("%s #%d", name, i+1)
+	}
+	return name
+}
+
+// ForgeName implements Namer: the sinks joined by commas, which --forge
+// accepts back as a list so nested commands (via CI_STATUS_FORGE) report to
+// the same sinks. Sinks are named by their --forge value when known
+// ("gitea,github=mirror"), otherwise by their distinct forge names.
+func (m *MultiClient) ForgeName() string {
+	var names []string
+	for i, c := range m.Clients {
+		n := Name(c)
+		if i < len(m.Specs) && m.Specs[i] != "" {
+			n = m.Specs[i]
+		}
+		if n != "" && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,121 @@
+package forge_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"ci-status/internal/forge"
+)
+
+// namedClient is a countingClient reporting a forge name.
+type namedClient struct {
+	countingClient
+	name string
+}
+
+func (c *namedClient) ForgeName() string { return c.name }
+
+func TestParseSink(t *testing.T) {
+	cases := map[string]forge.Sink{
+		"github":                             {Forge: "github"},
+		"github=mirror":                      {Forge: "github", Remote: "mirror"},
+		"=https://gitea.example.com/o/r.git": {Remote: "https://gitea.example.com/o/r.git"},
+	}
+	for spec, want := range cases {
+		if got := forge.ParseSink(spec); got != want {
+			t.Errorf("ParseSink(%q) = %+v, want %+v", spec, got, want)
+		}
+	}
+}
+
+func TestMultiClientPostsToEverySink(t *testing.T) {
+	gitea := &namedClient{name: "gitea"}
+	github := &namedClient{name: "github"}
+	m := &forge.MultiClient{Clients: []forge.ForgeClient{gitea, github}, RequireAll: true}
+
+	if err := m.SetStatus(t.Context(), forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateSuccess}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if gitea.posts != 1 || github.posts != 1 {
+		t.Fatalf("posts = %d, %d, want one each", gitea.posts, github.posts)
+	}
+	if got := forge.Name(m); got != "gitea,github" {
+		t.Fatalf("ForgeName = %q", got)
+	}
+}
+
+func TestMultiClientPolicy(t *testing.T) {
+	opts := forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateSuccess}
+
+	t.Run("all", func(t *testing.T) {
+		m := &forge.MultiClient{
+			Clients:    []forge.ForgeClient{&namedClient{name: "gitea"}, &namedClient{name: "github", countingClient: countingClient{err: errors.New("401")}}},
+			RequireAll: true,
+		}
+		err := m.SetStatus(t.Context(), opts)
+		if err == nil || !strings.Contains(err.Error(), "github: 401") {
+			t.Fatalf("err = %v, want the github failure", err)
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		var mu sync.Mutex
+		var partial []error
+		m := &forge.MultiClient{
+			Clients: []forge.ForgeClient{&namedClient{name: "gitea"}, &namedClient{name: "github", countingClient: countingClient{err: errors.New("401")}}},
+			OnPartialError: func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				partial = append(partial, err)
+			},
+		}
+		if err := m.SetStatus(t.Context(), opts); err != nil {
+			t.Fatalf("SetStatus: %v", err)
+		}
+		if len(partial) != 1 || !strings.Contains(partial[0].Error(), "github: 401") {
+			t.Fatalf("partial errors = %v", partial)
+		}
+	})
+
+	t.Run("any all failed", func(t *testing.T) {
+		m := &forge.MultiClient{Clients: []forge.ForgeClient{
+			&namedClient{name: "github", countingClient: countingClient{err: errors.New("401")}},
+			&namedClient{name: "github", countingClient: countingClient{err: errors.New("502")}},
+		}}
+		err := m.SetStatus(t.Context(), opts)
+		if err == nil {
+			t.Fatal("expected an error when every sink failed")
+		}
+		for _, want := range []string{"github #1: 401", "github #2: 502"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("err = %v, want %q", err, want)
+			}
+		}
+	})
+}
+
+func TestDetectClientsRemotes(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	client, err := forge.DetectClients([]string{
+		"gitea=https://gitea.example.com/owner/repo.git",
+		"github=https://github.com/owner/repo.git",
+		"github=not-a-remote",
+	}, forge.Sink{}, false)
+	if client == nil {
+		t.Fatalf("expected a client for the detected sinks, err %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "--forge github=not-a-remote") {
+		t.Fatalf("err = %v, want the undetected sink", err)
+	}
+	m, ok := client.(*forge.MultiClient)
+	if !ok || len(m.Clients) != 2 {
+		t.Fatalf("client = %#v, want a MultiClient over two sinks", client)
+	}
+	want := "gitea=https://gitea.example.com/owner/repo.git,github=https://github.com/owner/repo.git"
+	if got := forge.Name(client); got != want {
+		t.Fatalf("ForgeName = %q, want %q", got, want)
+	}
+}
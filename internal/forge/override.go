@@ -0,0 +1,117 @@
+package forge
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"ci-status/internal/credentials"
+)
+
+// Override error table for --repo and --api-url.
+const (
+	ErrInvalidRepo   detectError = "invalid repository (want owner/name)"
+	ErrInvalidAPIURL detectError = "invalid api url"
+	ErrNoAPIURL      detectError = "cannot tell the forge API from the remote; set --api-url"
+)
+
+// repoSegment is the allowlist for owner and repository names given on the
+// command line. They are pasted into API paths, so anything else (slashes,
+// percent escapes, whitespace) is rejected rather than sanitized.
+var repoSegment = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// ParseRepo splits an "owner/name" override. Both segments must match
+// repoSegment and may not be "." or "..".
+func ParseRepo(spec string) (owner, repo string, err error) {
+	owner, repo, ok := strings.Cut(spec, "/")
+	if !ok || !validRepoSegment(owner) || !validRepoSegment(repo) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidRepo, spec)
+	}
+	return owner, repo, nil
+}
+
+func validRepoSegment(s string) bool {
+	return repoSegment.MatchString(s) && s != "." && s != ".."
+}
+
+// parseAPIURL validates an --api-url override: an absolute http(s) URL
+// without credentials, query or fragment. The trailing slash is dropped so
+// status paths can be appended.
+func parseAPIURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrInvalidAPIURL, raw, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%w %q (want http(s)://host[/path])", ErrInvalidAPIURL, raw)
+	}
+	return strings.TrimSuffix(raw, "/"), nil
+}
+
+// clientForRepo builds the client for a --repo override directly, without
+// parsing owner and repository from a remote.
+//
+// The API base URL is, in order: --api-url; for gitea (by --forge, or
+// auto-detected because the remote is a non-GitHub host outside GitHub
+// Actions) the remote host's /api/v1; otherwise GITHUB_API_URL or
+// api.github.com. The remote is only consulted when it is needed. The token
+// is looked up for the API host once it is known.
+func clientForRepo(s Sink, creds *credentials.Resolver) (ForgeClient, error) {
+	owner, repo, err := ParseRepo(s.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if s.Forge != "" && s.Forge != "github" && s.Forge != "gitea" {
+		return nil, fmt.Errorf("%w %q (supported: github, gitea)", ErrUnsupportedForgeOverride, s.Forge)
+	}
+	client := NewGitHubClient("", owner, repo)
+	switch {
+	case s.APIURL != "":
+		client.BaseURL, err = parseAPIURL(s.APIURL)
+		if err != nil {
+			return nil, err
+		}
+	case s.Forge == "gitea" || (s.Forge == "" && !githubActionsEnvPresent()):
+		base, err := giteaAPIURL(s.Remote)
+		if err == nil {
+			client.BaseURL = base
+		} else if s.Forge == "gitea" {
+			return nil, err
+		}
+	}
+	if client.BaseURL == "" {
+		if apiURL := strings.TrimSpace(os.Getenv("GITHUB_API_URL")); apiURL != "" {
+			client.BaseURL = strings.TrimSuffix(apiURL, "/")
+		}
+	}
+
+	token, err := creds.Lookup(tokenRequest(client, ""))
+	if err != nil {
+		return nil, credentialsError(err, "--repo "+s.Repo)
+	}
+	client.setToken(token)
+	return client, nil
+}
+
+// giteaAPIURL derives a Gitea/Forgejo API base URL from the host of remote
+// (or of origin/upstream when empty), like LoadGeneric. GitHub hosts are
+// an error so auto-detection falls back to the GitHub API.
+func giteaAPIURL(remote string) (string, error) {
+	var remoteURL string
+	var err error
+	if remote != "" {
+		remoteURL, err = resolveRemote(remote)
+	} else {
+		remoteURL, err = getOriginURL()
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNoAPIURL, err)
+	}
+	host, scheme := getHostAndScheme(remoteURL)
+	if host == "" || isGitHubAPIHost(host) {
+		return "", fmt.Errorf("%w (remote %s)", ErrNoAPIURL, remoteURL)
+	}
+	return fmt.Sprintf("%s://%s/api/v1", scheme, host), nil
+}
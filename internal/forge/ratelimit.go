@@ -0,0 +1,48 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitError is returned by SetStatus when the API rejected a request for
+// exceeding a rate limit (HTTP 429, or 403 with rate-limit headers). Callers
+// posting many statuses can wait RetryAfter and try again.
+type RateLimitError struct {
+	// RetryAfter is how long the API asked to wait, from Retry-After or
+	// X-RateLimit-Reset; zero when it did not say.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limited: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// rateLimited wraps err in a RateLimitError when resp is a rate-limit
+// rejection, and returns err unchanged otherwise.
+func rateLimited(resp *http.Response, err error) error {
+	retryAfter := resp.Header.Get("Retry-After")
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusForbidden && (retryAfter != "" || remaining == "0"):
+	default:
+		return err
+	}
+
+	rl := &RateLimitError{Err: err}
+	if secs, convErr := strconv.Atoi(retryAfter); convErr == nil && secs > 0 {
+		rl.RetryAfter = time.Duration(secs) * time.Second
+	} else if reset, convErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); convErr == nil && remaining == "0" {
+		rl.RetryAfter = max(time.Until(time.Unix(reset, 0)), 0)
+	}
+	return rl
+}
@@ -0,0 +1,56 @@
+package forge_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ci-status/internal/forge"
+)
+
+func TestSetStatusReportsRateLimits(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		headers   map[string]string
+		wantRL    bool
+		wantAfter time.Duration
+	}{
+		{"429 retry-after", http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}, true, 7 * time.Second},
+		{"429 without hint", http.StatusTooManyRequests, nil, true, 0},
+		{"403 secondary limit", http.StatusForbidden, map[string]string{"Retry-After": "60"}, true, time.Minute},
+		{"403 primary limit", http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10),
+		}, true, 30 * time.Second},
+		{"403 forbidden", http.StatusForbidden, nil, false, 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(srv.Close)
+
+			client := forge.NewGitHubClient("token", "owner", "repo")
+			client.BaseURL = srv.URL
+			err := client.SetStatus(t.Context(), forge.StatusOpts{Commit: "abc", Context: "lint", State: forge.StateSuccess})
+			if !errors.Is(err, forge.ErrGitHubAPIError) {
+				t.Fatalf("err = %v, want ErrGitHubAPIError", err)
+			}
+			var rl *forge.RateLimitError
+			if got := errors.As(err, &rl); got != tt.wantRL {
+				t.Fatalf("RateLimitError = %v, want %v (err %v)", got, tt.wantRL, err)
+			}
+			if rl != nil && (rl.RetryAfter > tt.wantAfter || rl.RetryAfter < tt.wantAfter-2*time.Second) {
+				t.Fatalf("RetryAfter = %s, want about %s", rl.RetryAfter, tt.wantAfter)
+			}
+		})
+	}
+}
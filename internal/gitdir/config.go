@@ -0,0 +1,237 @@
+package gitdir
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Config is a parsed git config file. Section and key names are
+// case-insensitive; subsection names (the quoted part of [remote "origin"])
+// are not.
+type Config struct {
+	entries []configEntry
+}
+
+type configEntry struct {
+	section, subsection, key, value string
+}
+
+// LoadConfig parses the git config file at path; a missing file is an
+// empty config.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	c, err := ParseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParseConfig parses git config syntax: [section], [section "subsection"]
+// and the deprecated [section.subsection] headers; "key = value" lines,
+// where a key without "=" is true; double-quoted parts with \" \\ \n \t
+// escapes; # and ; comments outside quotes; and trailing-backslash line
+// continuations.
+func ParseConfig(r io.Reader) (*Config, error) {
+	c := &Config{}
+	var section, subsection string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Continuations end in an unescaped backslash.
+		for strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) && scanner.Scan() {
+			n++
+			line = line[:len(line)-1] + scanner.Text()
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			var rest string
+			var err error
+			section, subsection, rest, err = parseHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			line = strings.TrimSpace(rest)
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: key outside a section", n)
+		}
+
+		key, raw, hasValue := strings.Cut(line, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value := "true"
+		if hasValue {
+			var err error
+			if value, err = parseValue(raw); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+		} else if i := strings.IndexAny(key, "#;"); i >= 0 {
+			key = strings.TrimSpace(key[:i])
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", n)
+		}
+		c.entries = append(c.entries, configEntry{section, subsection, key, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseHeader parses a section header, returning what follows the closing
+// bracket (a key may share the line).
+func parseHeader(line string) (section, subsection, rest string, err error) {
+	end := strings.IndexByte(line, ']')
+	if end < 0 {
+		return "", "", "", fmt.Errorf("unterminated section header %q", line)
+	}
+	inner := line[1:end]
+	if name, quoted, ok := strings.Cut(inner, " "); ok {
+		quoted = strings.TrimSpace(quoted)
+		if len(quoted) < 2 || quoted[0] != '"' {
+			return "", "", "", fmt.Errorf("invalid section header %q", line)
+		}
+		// The closing bracket may be inside the quotes.
+		closing := strings.Index(line, `"]`)
+		if closing < 0 {
+			return "", "", "", fmt.Errorf("invalid section header %q", line)
+		}
+		quoted = line[strings.IndexByte(line, '"')+1 : closing]
+		subsection = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(quoted)
+		return strings.ToLower(name), subsection, line[closing+2:], nil
+	}
+	if name, sub, ok := strings.Cut(inner, "."); ok {
+		return strings.ToLower(name), strings.ToLower(sub), line[end+1:], nil
+	}
+	return strings.ToLower(inner), "", line[end+1:], nil
+}
+
+// parseValue unquotes a value and strips its trailing comment.
+func parseValue(raw string) (string, error) {
+	var b strings.Builder
+	quoted := false
+	// pending holds unquoted whitespace, kept only if more text follows.
+	pending := ""
+	raw = strings.TrimSpace(raw)
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '"':
+			b.WriteString(pending)
+			pending = ""
+			quoted = !quoted
+		case c == '\\':
+			if i+1 == len(raw) {
+				return "", fmt.Errorf("trailing backslash in %q", raw)
+			}
+			i++
+			b.WriteString(pending)
+			pending = ""
+			switch raw[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteString("\b")
+			case '"', '\\':
+				b.WriteByte(raw[i])
+			default:
+				return "", fmt.Errorf("invalid escape \\%c in %q", raw[i], raw)
+			}
+		case !quoted && (c == '#' || c == ';'):
+			return b.String(), nil
+		case !quoted && (c == ' ' || c == '\t'):
+			pending += string(c)
+		default:
+			b.WriteString(pending)
+			pending = ""
+			b.WriteByte(c)
+		}
+	}
+	if quoted {
+		return "", fmt.Errorf("unterminated quote in %q", raw)
+	}
+	return b.String(), nil
+}
+
+// Get returns the last value of section.subsection.key, as git does for
+// single-valued keys.
+func (c *Config) Get(section, subsection, key string) (string, bool) {
+	values := c.GetAll(section, subsection, key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns every value of section.subsection.key in file order.
+func (c *Config) GetAll(section, subsection, key string) []string {
+	var values []string
+	for _, e := range c.entries {
+		if e.section == strings.ToLower(section) && e.subsection == subsection && e.key == strings.ToLower(key) {
+			values = append(values, e.value)
+		}
+	}
+	return values
+}
+
+// Bool reports whether section.subsection.key is set to a true value
+// (true, yes, on or 1; a key without value is true).
+func (c *Config) Bool(section, subsection, key string) bool {
+	v, _ := c.Get(section, subsection, key)
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// HasSection reports whether any key of section (in any subsection) is set.
+func (c *Config) HasSection(section string) bool {
+	for _, e := range c.entries {
+		if e.section == strings.ToLower(section) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge appends other's entries so they win over c's.
+func (c *Config) merge(other *Config) {
+	c.entries = append(c.entries, other.entries...)
+}
+
+// rewriteURL applies the url.<base>.insteadOf rule with the longest
+// matching prefix, like git does for fetch URLs.
+func (c *Config) rewriteURL(url string) string {
+	var base, match string
+	for _, e := range c.entries {
+		if e.section == "url" && e.key == "insteadof" && strings.HasPrefix(url, e.value) && len(e.value) > len(match) {
+			base, match = e.subsection, e.value
+		}
+	}
+	if match == "" {
+		return url
+	}
+	return base + strings.TrimPrefix(url, match)
+}
@@ -0,0 +1,68 @@
+package gitdir_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"ci-status/internal/gitdir"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := gitdir.ParseConfig(strings.NewReader(`# comment
+[Core]
+	Bare = false ; trailing comment
+	logAllRefUpdates
+[remote "Origin"]
+	url = "https://example.com/a b.git" # quoted
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+[branch.main]
+	remote = origin
+[alias]
+	lg = log \
+--oneline
+	q = "say \"hi\"\tnow"
+`))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+
+	for _, tt := range []struct {
+		section, subsection, key, want string
+	}{
+		{"core", "", "bare", "false"},
+		{"CORE", "", "logallrefupdates", "true"},
+		{"remote", "Origin", "URL", "https://example.com/a b.git"},
+		{"branch", "main", "remote", "origin"},
+		{"alias", "", "lg", "log --oneline"},
+		{"alias", "", "q", "say \"hi\"\tnow"},
+	} {
+		if got, ok := c.Get(tt.section, tt.subsection, tt.key); !ok || got != tt.want {
+			t.Errorf("Get(%s, %s, %s) = %q, %t; want %q", tt.section, tt.subsection, tt.key, got, ok, tt.want)
+		}
+	}
+	if _, ok := c.Get("remote", "origin", "url"); ok {
+		t.Error("subsection names must be case-sensitive")
+	}
+	if got := c.GetAll("remote", "Origin", "fetch"); !slices.Equal(got, []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}) {
+		t.Errorf("GetAll fetch = %q", got)
+	}
+	if c.Bool("core", "", "bare") || !c.Bool("core", "", "logallrefupdates") {
+		t.Error("Bool misread core.bare or a valueless key")
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, in := range []string{
+		"key = value\n",
+		"[core\n",
+		"[remote origin]\n",
+		"[core]\n\tname = \"unterminated\n",
+		"[core]\n\tname = bad \\x escape\n",
+	} {
+		if _, err := gitdir.ParseConfig(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseConfig(%q) should fail", in)
+		}
+	}
+}
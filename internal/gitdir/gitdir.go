@@ -0,0 +1,287 @@
+// Package gitdir reads repository metadata (remote URLs, HEAD) straight
+// from the .git directory, so detection works in images without a git
+// binary and stays fast on huge repositories.
+//
+// It only covers the files detection needs: config, HEAD, loose refs and
+// packed-refs, in plain repositories and linked worktrees (a .git file
+// pointing at the worktree's git dir, which names the shared one in
+// commondir). GIT_DIR, GIT_COMMON_DIR and GIT_WORK_TREE are honoured like
+// git does. Layouts it does not understand (reftable, config includes)
+// return ErrUnsupported so callers can fall back to the git binary.
+package gitdir
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotRepository = errors.New("not a git repository")
+	ErrUnsupported   = errors.New("repository layout not supported without git")
+	ErrNoRemote      = errors.New("no such remote")
+	ErrRefNotFound   = errors.New("ref not found")
+)
+
+// maxSymrefDepth bounds "ref: ..." chains (git itself stops at 5).
+const maxSymrefDepth = 5
+
+// Repo is an opened repository.
+type Repo struct {
+	// GitDir holds the per-worktree files (HEAD, worktree-local refs).
+	GitDir string
+	// CommonDir holds what worktrees share (config, refs, packed-refs);
+	// it equals GitDir outside linked worktrees.
+	CommonDir string
+	// WorkTree is the checkout's top-level directory; empty for bare
+	// repositories opened through GIT_DIR.
+	WorkTree string
+
+	config *Config
+}
+
+// Open finds the repository containing dir (the working directory when
+// empty). GIT_DIR replaces discovery, with GIT_WORK_TREE (or core.worktree,
+// or dir) as the work tree; otherwise dir and its parents are searched for
+// .git, a directory or a "gitdir: ..." file.
+func Open(dir string) (*Repo, error) {
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = wd
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Repo{}
+	if env := os.Getenv("GIT_DIR"); env != "" {
+		r.GitDir = absFrom(dir, env)
+		if !isGitDir(r.GitDir) {
+			return nil, fmt.Errorf("%w: GIT_DIR %s", ErrNotRepository, r.GitDir)
+		}
+		r.WorkTree = dir
+	} else if err := r.discover(dir); err != nil {
+		return nil, err
+	}
+
+	r.CommonDir = r.GitDir
+	if env := os.Getenv("GIT_COMMON_DIR"); env != "" {
+		r.CommonDir = absFrom(dir, env)
+	} else if data, err := os.ReadFile(filepath.Join(r.GitDir, "commondir")); err == nil {
+		r.CommonDir = absFrom(r.GitDir, strings.TrimSpace(string(data)))
+	}
+
+	if err := r.loadConfig(); err != nil {
+		return nil, err
+	}
+
+	if env := os.Getenv("GIT_WORK_TREE"); env != "" {
+		r.WorkTree = absFrom(dir, env)
+	} else if wt, ok := r.config.Get("core", "", "worktree"); ok && os.Getenv("GIT_DIR") != "" {
+		r.WorkTree = absFrom(r.GitDir, wt)
+	} else if r.config.Bool("core", "", "bare") {
+		r.WorkTree = ""
+	}
+	return r, nil
+}
+
+// loadConfig reads the shared config and, with extensions.worktreeConfig,
+// the worktree's config.worktree on top of it.
+func (r *Repo) loadConfig() error {
+	var err error
+	r.config, err = LoadConfig(filepath.Join(r.CommonDir, "config"))
+	if err != nil {
+		return err
+	}
+	if storage, _ := r.config.Get("extensions", "", "refstorage"); storage != "" && storage != "files" {
+		return fmt.Errorf("%w: %s ref storage", ErrUnsupported, storage)
+	}
+	if r.config.Bool("extensions", "", "worktreeconfig") {
+		wt, err := LoadConfig(filepath.Join(r.GitDir, "config.worktree"))
+		if err != nil {
+			return err
+		}
+		r.config.merge(wt)
+	}
+	// Included files could hold anything, remotes included.
+	if r.config.HasSection("include") || r.config.HasSection("includeif") {
+		return fmt.Errorf("%w: config includes", ErrUnsupported)
+	}
+	return nil
+}
+
+// discover walks up from dir to the nearest .git.
+func (r *Repo) discover(dir string) error {
+	for d := dir; ; {
+		dotGit := filepath.Join(d, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil {
+			r.WorkTree = d
+			if info.IsDir() {
+				r.GitDir = dotGit
+				return nil
+			}
+			r.GitDir, err = readGitFile(dotGit)
+			return err
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return fmt.Errorf("%w: %s", ErrNotRepository, dir)
+		}
+		d = parent
+	}
+}
+
+// readGitFile follows the "gitdir: <path>" file worktrees and submodules
+// have instead of a .git directory.
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not a gitdir file", ErrNotRepository, path)
+	}
+	gitDir := absFrom(filepath.Dir(path), strings.TrimSpace(target))
+	if !isGitDir(gitDir) {
+		return "", fmt.Errorf("%w: %s points to %s", ErrNotRepository, path, gitDir)
+	}
+	return gitDir, nil
+}
+
+// isGitDir reports whether dir looks like a git directory.
+func isGitDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "HEAD"))
+	return err == nil
+}
+
+// absFrom resolves path relative to base.
+func absFrom(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+// Config returns the repository's parsed config file.
+func (r *Repo) Config() *Config {
+	return r.config
+}
+
+// RemoteURL returns remote.<name>.url with url.<base>.insteadOf rewrites
+// applied, as 'git remote get-url' prints it.
+func (r *Repo) RemoteURL(name string) (string, error) {
+	url, ok := r.config.Get("remote", name, "url")
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrNoRemote, name)
+	}
+	return r.config.rewriteURL(url), nil
+}
+
+// Head resolves HEAD to a commit SHA.
+func (r *Repo) Head() (string, error) {
+	return r.Resolve("HEAD")
+}
+
+// Resolve returns the object ID a ref points to, following symbolic refs.
+// Names are full ref names ("HEAD", "refs/heads/main").
+func (r *Repo) Resolve(name string) (string, error) {
+	for range maxSymrefDepth {
+		value, err := r.readRef(name)
+		if err != nil {
+			return "", err
+		}
+		target, symbolic := strings.CutPrefix(value, "ref:")
+		if !symbolic {
+			if !isObjectID(value) {
+				return "", fmt.Errorf("%w: %s holds %q", ErrUnsupported, name, value)
+			}
+			return value, nil
+		}
+		name = strings.TrimSpace(target)
+	}
+	return "", fmt.Errorf("%w: symbolic ref loop at %s", ErrRefNotFound, name)
+}
+
+// readRef returns the raw value of one ref: the loose file (per-worktree
+// refs in GitDir, shared ones in CommonDir), then packed-refs.
+func (r *Repo) readRef(name string) (string, error) {
+	dir := r.CommonDir
+	if perWorktree(name) {
+		dir = r.GitDir
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	return r.packedRef(name)
+}
+
+// perWorktree reports whether git keeps ref in each worktree's own git
+// dir: pseudo refs like HEAD, and refs/worktree, refs/bisect, refs/rewritten.
+func perWorktree(ref string) bool {
+	if !strings.HasPrefix(ref, "refs/") {
+		return true
+	}
+	for _, prefix := range []string{"refs/worktree/", "refs/bisect/", "refs/rewritten/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// packedRef looks name up in packed-refs ("<oid> <ref>" lines; "#" header
+// and "^<oid>" peeled lines are skipped).
+func (r *Repo) packedRef(name string) (string, error) {
+	f, err := os.Open(filepath.Join(r.CommonDir, "packed-refs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		oid, ref, ok := strings.Cut(line, " ")
+		if ok && ref == name {
+			return oid, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+}
+
+// isObjectID accepts SHA-1 and SHA-256 hex object IDs.
+func isObjectID(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
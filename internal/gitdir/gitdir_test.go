@@ -0,0 +1,204 @@
+package gitdir_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ci-status/internal/gitdir"
+)
+
+const (
+	shaMain    = "1111111111111111111111111111111111111111"
+	shaFeature = "2222222222222222222222222222222222222222"
+	shaPacked  = "3333333333333333333333333333333333333333"
+)
+
+// writeFiles creates files (slash-separated paths) under root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// clearGitEnv keeps the test's repositories from being overridden by a
+// surrounding git hook or CI job.
+func clearGitEnv(t *testing.T) {
+	for _, env := range []string{"GIT_DIR", "GIT_COMMON_DIR", "GIT_WORK_TREE"} {
+		t.Setenv(env, "") // restored after the test
+		_ = os.Unsetenv(env)
+	}
+}
+
+// fakeRepo lays out a repository with a loose and a packed branch and a
+// linked worktree "wt" checked out on the packed one.
+func fakeRepo(t *testing.T) string {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":            "ref: refs/heads/main\n",
+		".git/refs/heads/main": shaMain + "\n",
+		".git/packed-refs": "# pack-refs with: peeled fully-peeled sorted\n" +
+			shaFeature + " refs/heads/feature\n" +
+			shaPacked + " refs/tags/v1\n" +
+			"^" + shaMain + "\n",
+		".git/config": "[core]\n\tbare = false\n" +
+			"[remote \"origin\"]\n\turl = git@github.com:owner/repo.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n" +
+			"[remote \"mirror\"]\n\turl = gh:owner/mirror\n" +
+			"[url \"https://github.com/\"]\n\tinsteadOf = gh:\n",
+		".git/worktrees/wt/HEAD":      "ref: refs/heads/feature\n",
+		".git/worktrees/wt/commondir": "../..\n",
+		"wt/.git":                     "gitdir: ../.git/worktrees/wt\n",
+		"sub/dir/file.txt":            "",
+	})
+	return root
+}
+
+func TestOpenDiscoversRepository(t *testing.T) {
+	clearGitEnv(t)
+	root := fakeRepo(t)
+
+	repo, err := gitdir.Open(filepath.Join(root, "sub", "dir"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if repo.WorkTree != root || repo.GitDir != filepath.Join(root, ".git") || repo.CommonDir != repo.GitDir {
+		t.Fatalf("repo = %+v", repo)
+	}
+	if sha, err := repo.Head(); err != nil || sha != shaMain {
+		t.Fatalf("Head = %q, %v; want %s", sha, err, shaMain)
+	}
+	if sha, err := repo.Resolve("refs/tags/v1"); err != nil || sha != shaPacked {
+		t.Fatalf("packed tag = %q, %v", sha, err)
+	}
+	if url, err := repo.RemoteURL("origin"); err != nil || url != "git@github.com:owner/repo.git" {
+		t.Fatalf("origin = %q, %v", url, err)
+	}
+	if url, err := repo.RemoteURL("mirror"); err != nil || url != "https://github.com/owner/mirror" {
+		t.Fatalf("mirror = %q, %v (insteadOf not applied)", url, err)
+	}
+	if _, err := repo.RemoteURL("upstream"); !errors.Is(err, gitdir.ErrNoRemote) {
+		t.Fatalf("upstream: %v, want ErrNoRemote", err)
+	}
+}
+
+func TestOpenWorktree(t *testing.T) {
+	clearGitEnv(t)
+	root := fakeRepo(t)
+
+	repo, err := gitdir.Open(filepath.Join(root, "wt"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if repo.GitDir != filepath.Join(root, ".git", "worktrees", "wt") || repo.CommonDir != filepath.Join(root, ".git") {
+		t.Fatalf("repo = %+v", repo)
+	}
+	// HEAD is per worktree; the branch and the config are shared.
+	if sha, err := repo.Head(); err != nil || sha != shaFeature {
+		t.Fatalf("Head = %q, %v; want %s", sha, err, shaFeature)
+	}
+	if _, err := repo.RemoteURL("origin"); err != nil {
+		t.Fatalf("origin: %v", err)
+	}
+}
+
+func TestOpenGitDirEnv(t *testing.T) {
+	clearGitEnv(t)
+	root := fakeRepo(t)
+	elsewhere := t.TempDir()
+	t.Setenv("GIT_DIR", filepath.Join(root, ".git"))
+	t.Setenv("GIT_WORK_TREE", root)
+
+	repo, err := gitdir.Open(elsewhere)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if repo.WorkTree != root {
+		t.Fatalf("WorkTree = %q, want GIT_WORK_TREE %q", repo.WorkTree, root)
+	}
+	if sha, err := repo.Head(); err != nil || sha != shaMain {
+		t.Fatalf("Head = %q, %v", sha, err)
+	}
+
+	t.Setenv("GIT_DIR", elsewhere)
+	if _, err := gitdir.Open(root); !errors.Is(err, gitdir.ErrNotRepository) {
+		t.Fatalf("GIT_DIR without HEAD: %v, want ErrNotRepository", err)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	clearGitEnv(t)
+
+	if _, err := gitdir.Open(t.TempDir()); !errors.Is(err, gitdir.ErrNotRepository) {
+		t.Fatalf("outside a repository: %v", err)
+	}
+
+	for name, config := range map[string]string{
+		"reftable": "[extensions]\n\trefStorage = reftable\n",
+		"include":  "[include]\n\tpath = remotes.inc\n",
+	} {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{".git/HEAD": "ref: refs/heads/main\n", ".git/config": config})
+		if _, err := gitdir.Open(root); !errors.Is(err, gitdir.ErrUnsupported) {
+			t.Errorf("%s: %v, want ErrUnsupported", name, err)
+		}
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{".git/HEAD": "ref: refs/heads/main\n"})
+	repo, err := gitdir.Open(root)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := repo.Head(); !errors.Is(err, gitdir.ErrRefNotFound) {
+		t.Fatalf("unborn branch: %v, want ErrRefNotFound", err)
+	}
+}
+
+// TestMatchesGit compares the reader with the git binary on a real repository.
+func TestMatchesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	clearGitEnv(t)
+	root := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "first")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	git("remote", "add", "origin", "https://gitea.example.com/owner/repo.git")
+	git("pack-refs", "--all") // the branch now only lives in packed-refs
+	git("worktree", "add", "-q", "--detach", "wt", "HEAD~1")
+
+	for _, dir := range []string{root, filepath.Join(root, "wt")} {
+		repo, err := gitdir.Open(dir)
+		if err != nil {
+			t.Fatalf("Open(%s): %v", dir, err)
+		}
+		want := git("-C", dir, "rev-parse", "HEAD")
+		if sha, err := repo.Head(); err != nil || sha != want {
+			t.Fatalf("%s: Head = %q, %v; git says %s", dir, sha, err, want)
+		}
+		if url, err := repo.RemoteURL("origin"); err != nil || url != git("remote", "get-url", "origin") {
+			t.Fatalf("%s: origin = %q, %v", dir, url, err)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Package githubapp authenticates as a GitHub App: it signs a JWT with the
+// app's private key, finds the app's installation on a repository and
+// exchanges the JWT for an installation token. Installation tokens work where
+// personal tokens and the Actions GITHUB_TOKEN do not, e.g. on external CI or
+// for forks the app is installed on.
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrIncomplete is returned when only one of the app id and the key
+	// file is given.
+	ErrIncomplete = errors.New("--github-app-id and --github-app-key-file must be given together")
+	// ErrInvalidAppID is returned for app ids outside appIDPattern.
+	ErrInvalidAppID = errors.New("invalid github app id")
+	// ErrInvalidKey is returned for key files without an RSA private key.
+	ErrInvalidKey = errors.New("invalid github app private key")
+	// ErrNotInstalled is returned when the app has no installation on the
+	// repository.
+	ErrNotInstalled = errors.New("github app is not installed on the repository")
+	// ErrAPI wraps unexpected responses from the app endpoints.
+	ErrAPI = errors.New("github app api error")
+)
+
+// appIDPattern allows numeric app ids and client ids ("Iv23li...").
+var appIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+const (
+	// jwtLifetime stays under GitHub's 10 minute maximum; iat is backdated
+	// by jwtClockSkew for runners whose clock is slightly ahead.
+	jwtLifetime  = 9 * time.Minute
+	jwtClockSkew = time.Minute
+	// refreshMargin renews installation tokens (valid for an hour) this
+	// long before they expire, so a request never carries a stale one.
+	refreshMargin = 5 * time.Minute
+)
+
+// App is a GitHub App identity.
+type App struct {
+	// ID is the app id or client id, used as the JWT issuer.
+	ID  string
+	Key *rsa.PrivateKey
+	// InstallationID skips looking the installation up per repository.
+	InstallationID int64
+	// HTTPClient defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+}
+
+// Load builds an App from the --github-app-* flags. An empty id and key file
+// mean no app (nil, nil).
+func Load(id, keyFile string, installationID int64) (*App, error) {
+	if id == "" && keyFile == "" {
+		return nil, nil
+	}
+	if id == "" || keyFile == "" {
+		return nil, ErrIncomplete
+	}
+	if !appIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAppID, id)
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read github app key: %w", err)
+	}
+	key, err := ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, keyFile)
+	}
+	return &App{ID: id, Key: key, InstallationID: installationID}, nil
+}
+
+// ParseKey parses a PEM encoded RSA private key, PKCS#1 (as GitHub
+// generates them) or PKCS#8.
+func ParseKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block", ErrInvalidKey)
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: not an RSA key", ErrInvalidKey)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidKey, block.Type)
+	}
+}
+
+// JWT returns the app's RS256 signed token for the app endpoints, valid from
+// a minute before now for jwtLifetime.
+func (a *App) JWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": a.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign github app jwt: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Installation returns the token source for owner/repo at the API base URL
+// apiURL ("https://api.github.com", or a GHES ".../api/v3").
+func (a *App) Installation(apiURL, owner, repo string) *Installation {
+	return &Installation{app: a, apiURL: apiURL, owner: owner, repo: repo}
+}
+
+// Installation mints installation tokens for one repository. Tokens are
+// cached and renewed shortly before they expire; it is safe for concurrent
+// use.
+type Installation struct {
+	app                 *App
+	apiURL, owner, repo string
+
+	mu      sync.Mutex
+	id      int64
+	token   string
+	expires time.Time
+}
+
+// AppID is the id of the app the tokens are minted for.
+func (i *Installation) AppID() string { return i.app.ID }
+
+// Token returns a valid installation token, minting one when there is none
+// or the cached one is about to expire.
+//
+// Flow:
+//  1. Sign a JWT with the app key.
+//  2. Unless App.InstallationID is set, GET /repos/{owner}/{repo}/installation
+//     (once; the id is kept).
+//  3. POST /app/installations/{id}/access_tokens, scoped to the repository
+//     and to statuses: write.
+func (i *Installation) Token(ctx context.Context) (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	if i.token != "" && now.Add(refreshMargin).Before(i.expires) {
+		return i.token, nil
+	}
+
+	jwt, err := i.app.JWT(now)
+	if err != nil {
+		return "", err
+	}
+	if i.id == 0 {
+		i.id = i.app.InstallationID
+	}
+	if i.id == 0 {
+		var installation struct {
+			ID int64 `json:"id"`
+		}
+		url := fmt.Sprintf("%s/repos/%s/%s/installation", i.apiURL, i.owner, i.repo)
+		status, err := i.do(ctx, jwt, "GET", url, nil, http.StatusOK, &installation)
+		if status == http.StatusNotFound {
+			return "", fmt.Errorf("%w (app %s, %s/%s)", ErrNotInstalled, i.app.ID, i.owner, i.repo)
+		}
+		if err != nil {
+			return "", err
+		}
+		i.id = installation.ID
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"repositories": []string{i.repo},
+		"permissions":  map[string]string{"statuses": "write"},
+	})
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", i.apiURL, i.id)
+	if _, err := i.do(ctx, jwt, "POST", url, body, http.StatusCreated, &token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("%w: POST %s returned no token", ErrAPI, url)
+	}
+	i.token, i.expires = token.Token, token.ExpiresAt
+	return i.token, nil
+}
+
+// do sends an app request authenticated with jwt and decodes the response
+// into out when the status is want. The status is returned even on error.
+func (i *Installation) do(ctx context.Context, jwt, method, url string, body []byte, want int, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := i.app.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("execute request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != want {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return resp.StatusCode, fmt.Errorf("%w: %s %s: %s - %s", ErrAPI, method, url, resp.Status, respBody)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: decode %s response: %w", ErrAPI, url, err)
+	}
+	return resp.StatusCode, nil
+}
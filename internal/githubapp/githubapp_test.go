@@ -0,0 +1,199 @@
+package githubapp_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ci-status/internal/githubapp"
+)
+
+// testKey is shared by the tests; generating RSA keys is slow.
+var testKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// verifyJWT checks the RS256 signature of token and returns its claims.
+func verifyJWT(t *testing.T, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("jwt %q: want 3 parts", token)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("jwt signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&testKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("jwt signature: %v", err)
+	}
+	var header, claims map[string]any
+	for i, v := range []*map[string]any{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("jwt part %d: %v", i, err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("jwt part %d: %v", i, err)
+		}
+	}
+	if header["alg"] != "RS256" {
+		t.Fatalf("jwt header = %v", header)
+	}
+	return claims
+}
+
+func TestJWT(t *testing.T) {
+	app := &githubapp.App{ID: "12345", Key: testKey}
+	now := time.Unix(1_700_000_000, 0)
+	token, err := app.JWT(now)
+	if err != nil {
+		t.Fatalf("JWT: %v", err)
+	}
+	claims := verifyJWT(t, token)
+	if claims["iss"] != "12345" || claims["iat"] != float64(now.Unix()-60) || claims["exp"] != float64(now.Unix()+9*60) {
+		t.Fatalf("claims = %v", claims)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, block *pem.Block) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1 := write("pkcs1.pem", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testKey)})
+	pkcs8File := write("pkcs8.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+	cert := write("cert.pem", &pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})
+
+	for _, path := range []string{pkcs1, pkcs8File} {
+		app, err := githubapp.Load("Iv23li.abc", path, 7)
+		if err != nil || app.ID != "Iv23li.abc" || app.InstallationID != 7 || !app.Key.Equal(testKey) {
+			t.Errorf("Load(%s) = %+v, %v", filepath.Base(path), app, err)
+		}
+	}
+	if app, err := githubapp.Load("", "", 0); app != nil || err != nil {
+		t.Errorf("no app configured: %+v, %v", app, err)
+	}
+
+	for _, tt := range []struct {
+		id, file string
+		want     error
+	}{
+		{"12345", "", githubapp.ErrIncomplete},
+		{"", pkcs1, githubapp.ErrIncomplete},
+		{"12 345", pkcs1, githubapp.ErrInvalidAppID},
+		{"12345", cert, githubapp.ErrInvalidKey},
+		{"12345", filepath.Join(dir, "missing.pem"), os.ErrNotExist},
+	} {
+		if _, err := githubapp.Load(tt.id, tt.file, 0); !errors.Is(err, tt.want) {
+			t.Errorf("Load(%q, %q) = %v, want %v", tt.id, filepath.Base(tt.file), err, tt.want)
+		}
+	}
+}
+
+// fakeGitHub serves the app endpoints for owner/repo with installation 42.
+type fakeGitHub struct {
+	lookups, exchanges atomic.Int32
+	expiresIn          time.Duration
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.Count(auth, ".") != 2 {
+		http.Error(w, "want app jwt", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/repos/owner/repo/installation":
+		f.lookups.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 42})
+	case r.Method == "POST" && r.URL.Path == "/app/installations/42/access_tokens":
+		n := f.exchanges.Add(1)
+		var body struct {
+			Repositories []string          `json:"repositories"`
+			Permissions  map[string]string `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Repositories) != 1 || body.Repositories[0] != "repo" || body.Permissions["statuses"] != "write" {
+			http.Error(w, "unexpected body", http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("ghs_installation%d", n),
+			"expires_at": time.Now().Add(f.expiresIn).UTC().Format(time.RFC3339),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestInstallationToken(t *testing.T) {
+	fake := &fakeGitHub{expiresIn: time.Hour}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	app := &githubapp.App{ID: "12345", Key: testKey}
+	ctx := context.Background()
+
+	installation := app.Installation(srv.URL, "owner", "repo")
+	for range 2 {
+		token, err := installation.Token(ctx)
+		if err != nil || token != "ghs_installation1" {
+			t.Fatalf("Token = %q, %v", token, err)
+		}
+	}
+	if fake.lookups.Load() != 1 || fake.exchanges.Load() != 1 {
+		t.Fatalf("lookups %d, exchanges %d; want the token cached", fake.lookups.Load(), fake.exchanges.Load())
+	}
+
+	if _, err := app.Installation(srv.URL, "owner", "other").Token(ctx); !errors.Is(err, githubapp.ErrNotInstalled) {
+		t.Fatalf("other repository: %v, want ErrNotInstalled", err)
+	}
+}
+
+func TestInstallationTokenRefresh(t *testing.T) {
+	// Tokens this close to expiry are renewed on every use.
+	fake := &fakeGitHub{expiresIn: time.Minute}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	app := &githubapp.App{ID: "12345", Key: testKey, InstallationID: 42}
+
+	installation := app.Installation(srv.URL, "owner", "repo")
+	for _, want := range []string{"ghs_installation1", "ghs_installation2"} {
+		token, err := installation.Token(context.Background())
+		if err != nil || token != want {
+			t.Fatalf("Token = %q, %v; want %q", token, err, want)
+		}
+	}
+	if fake.lookups.Load() != 0 {
+		t.Fatalf("InstallationID set, but the installation was looked up %d times", fake.lookups.Load())
+	}
+}
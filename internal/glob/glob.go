@@ -0,0 +1,72 @@
+// Package glob matches slash-separated paths against patterns with "**"
+// support, as used by --paths, --cache-key-files and config context keys.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches pattern. Both use "/" as separator.
+//
+// Each segment follows path.Match syntax (*, ?, [...]), where "*" never
+// crosses a "/". A segment that is exactly "**" matches zero or more whole
+// segments, so "web/**" matches "web/a.ts" and "web/x/y.ts", and "**/*.go"
+// matches Go files at any depth including the root. Malformed patterns never
+// match.
+func Match(pattern, name string) bool {
+	return matchSegments(split(pattern), split(name))
+}
+
+// MatchAny reports whether name matches at least one pattern.
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// split breaks a path into segments, ignoring a leading "./" and empty
+// segments from doubled or trailing slashes.
+func split(p string) []string {
+	p = strings.TrimPrefix(p, "./")
+	var parts []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return parts
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive ** and try every possible split point.
+			rest := pattern[1:]
+			for len(rest) > 0 && rest[0] == "**" {
+				rest = rest[1:]
+			}
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
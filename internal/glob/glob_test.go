@@ -0,0 +1,52 @@
+package glob_test
+
+import (
+	"testing"
+
+	"ci-status/internal/glob"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"web/**", "web/a.ts", true},
+		{"web/**", "web/x/y/z.ts", true},
+		{"web/**", "web", true},
+		{"web/**", "api/web/a.ts", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "internal/forge/github.go", true},
+		{"**/*.go", "internal/forge/github.go.orig", false},
+		{"*.go", "internal/main.go", false},
+		{"*.go", "main.go", true},
+		{"go.sum", "go.sum", true},
+		{"./go.sum", "go.sum", true},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"docs/**/*.md", "docs/c.md", true},
+		{"docs/**/**/*.md", "docs/c.md", true},
+		{"src/?.c", "src/a.c", true},
+		{"src/[ab].c", "src/c.c", false},
+		{"**", "anything/at/all", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := glob.Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	patterns := []string{"web/**", "package.json"}
+	if !glob.MatchAny(patterns, "package.json") {
+		t.Fatal("expected match on second pattern")
+	}
+	if glob.MatchAny(patterns, "api/main.go") {
+		t.Fatal("unexpected match")
+	}
+	if glob.MatchAny(nil, "x") {
+		t.Fatal("no patterns should never match")
+	}
+}
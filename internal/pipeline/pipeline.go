@@ -0,0 +1,311 @@
+// Package pipeline loads .ci-status.yml step definitions and executes them as
+// a dependency graph (DAG).
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFiles are the pipeline file names tried, in order, when no explicit
+// path is given.
+var DefaultFiles = []string{".ci-status.yml", ".ci-status.yaml"}
+
+// ErrNoPipelineFile is returned by Find when none of DefaultFiles exists.
+var ErrNoPipelineFile = errors.New("no pipeline file found (.ci-status.yml)")
+
+// Step is one named unit of work in a pipeline.
+type Step struct {
+	// Name identifies the step in depends_on lists.
+	Name string `yaml:"name"`
+	// Context is the status context posted to the forge. Defaults to Name.
+	Context string `yaml:"context"`
+	// Command is a script run through the platform shell.
+	Command string `yaml:"command"`
+	// Env holds extra environment variables for the command.
+	Env map[string]string `yaml:"env"`
+	// Timeout limits the step's run time (Go duration, e.g. "5m"). Zero means none.
+	Timeout time.Duration `yaml:"timeout"`
+	// DependsOn lists step names that must succeed before this step starts.
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// Pipeline is a validated, acyclic set of steps in file order.
+type Pipeline struct {
+	Steps []Step
+}
+
+// file mirrors the on-disk layout; other top-level keys are ignored so the
+// same file can carry unrelated ci-status settings.
+type file struct {
+	Steps []Step `yaml:"steps"`
+}
+
+// Find returns the first existing file from DefaultFiles.
+func Find() (string, error) {
+	for _, name := range DefaultFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", ErrNoPipelineFile
+}
+
+// Load reads and validates a pipeline file.
+func Load(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pipeline: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes pipeline YAML and validates it (see Validate).
+func Parse(data []byte) (*Pipeline, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse pipeline: %w", err)
+	}
+	p := &Pipeline{Steps: f.Steps}
+	for i := range p.Steps {
+		if p.Steps[i].Context == "" {
+			p.Steps[i].Context = p.Steps[i].Name
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks that steps are named uniquely, have commands, only depend
+// on known steps, and contain no dependency cycles. Contexts must be unique
+// too, or two steps would overwrite each other's status on the forge.
+func (p *Pipeline) Validate() error {
+	if len(p.Steps) == 0 {
+		return errors.New("pipeline has no steps")
+	}
+	names := map[string]bool{}
+	contexts := map[string]bool{}
+	for _, s := range p.Steps {
+		if s.Name == "" {
+			return errors.New("step without a name")
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicate step %q", s.Name)
+		}
+		names[s.Name] = true
+		if contexts[s.Context] {
+			return fmt.Errorf("duplicate step context %q", s.Context)
+		}
+		contexts[s.Context] = true
+		if strings.TrimSpace(s.Command) == "" {
+			return fmt.Errorf("step %q has no command", s.Name)
+		}
+	}
+	for _, s := range p.Steps {
+		for _, dep := range s.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("step %q depends on unknown step %q", s.Name, dep)
+			}
+			if dep == s.Name {
+				return fmt.Errorf("step %q depends on itself", s.Name)
+			}
+		}
+	}
+	return p.checkCycles()
+}
+
+// checkCycles runs Kahn's algorithm; steps left over are part of a cycle.
+func (p *Pipeline) checkCycles() error {
+	indegree := map[string]int{}
+	dependents := map[string][]string{}
+	for _, s := range p.Steps {
+		indegree[s.Name] = len(s.DependsOn)
+		for _, dep := range s.DependsOn {
+			dependents[dep] = append(dependents[dep], s.Name)
+		}
+	}
+	var queue []string
+	for _, s := range p.Steps {
+		if indegree[s.Name] == 0 {
+			queue = append(queue, s.Name)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, d := range dependents[name] {
+			indegree[d]--
+			if indegree[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	if visited == len(p.Steps) {
+		return nil
+	}
+	var cyclic []string
+	for name, n := range indegree {
+		if n > 0 {
+			cyclic = append(cyclic, name)
+		}
+	}
+	sort.Strings(cyclic)
+	return fmt.Errorf("dependency cycle between steps: %s", strings.Join(cyclic, ", "))
+}
+
+// Outcome is how a step ended.
+type Outcome int
+
+const (
+	// Succeeded means the step ran and RunFunc reported success.
+	Succeeded Outcome = iota
+	// Failed means the step ran and RunFunc reported failure.
+	Failed
+	// Skipped means the step never ran because a dependency did not succeed.
+	Skipped
+)
+
+// String returns the lowercase outcome name used in summaries.
+func (o Outcome) String() string {
+	switch o {
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	default:
+		return fmt.Sprintf("outcome(%d)", int(o))
+	}
+}
+
+// Environ renders Env as sorted KEY=VALUE entries for executor.Executor.Env.
+func (s Step) Environ() []string {
+	if len(s.Env) == 0 {
+		return nil
+	}
+	env := make([]string, 0, len(s.Env))
+	for k, v := range s.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// RunFunc executes one step and reports whether it succeeded.
+type RunFunc func(ctx context.Context, s Step) bool
+
+// SkipFunc is called once for every step skipped because failedDep (a direct
+// or transitive dependency) did not succeed.
+type SkipFunc func(s Step, failedDep string)
+
+// Execute runs steps as soon as all their dependencies succeeded, with at
+// most concurrency steps in flight. When a step fails, every step depending
+// on it (transitively) is skipped. It returns the outcome of every step.
+//
+// run is called from separate goroutines; skip is called from the caller's
+// goroutine. Steps that never become ready because ctx was cancelled are
+// reported as skipped with an empty failedDep.
+func (p *Pipeline) Execute(ctx context.Context, concurrency int, run RunFunc, skip SkipFunc) map[string]Outcome {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	byName := map[string]Step{}
+	waiting := map[string]int{}
+	dependents := map[string][]string{}
+	for _, s := range p.Steps {
+		byName[s.Name] = s
+		waiting[s.Name] = len(s.DependsOn)
+		for _, dep := range s.DependsOn {
+			dependents[dep] = append(dependents[dep], s.Name)
+		}
+	}
+
+	outcomes := map[string]Outcome{}
+	var ready []string
+	for _, s := range p.Steps {
+		if waiting[s.Name] == 0 {
+			ready = append(ready, s.Name)
+		}
+	}
+
+	type result struct {
+		name string
+		ok   bool
+	}
+	results := make(chan result)
+	running := 0
+
+	// skipDependents marks everything downstream of name as skipped.
+	var skipDependents func(name, cause string)
+	skipDependents = func(name, cause string) {
+		for _, d := range dependents[name] {
+			if _, done := outcomes[d]; done {
+				continue
+			}
+			outcomes[d] = Skipped
+			if skip != nil {
+				skip(byName[d], cause)
+			}
+			skipDependents(d, cause)
+		}
+	}
+
+	for len(ready) > 0 || running > 0 {
+		for len(ready) > 0 && running < concurrency && ctx.Err() == nil {
+			name := ready[0]
+			ready = ready[1:]
+			running++
+			go func(s Step) {
+				results <- result{name: s.Name, ok: run(ctx, s)}
+			}(byName[name])
+		}
+		if running == 0 {
+			// Cancelled with steps still ready: nothing will start them.
+			break
+		}
+
+		r := <-results
+		running--
+		if !r.ok {
+			outcomes[r.name] = Failed
+			skipDependents(r.name, r.name)
+			continue
+		}
+		outcomes[r.name] = Succeeded
+		for _, d := range dependents[r.name] {
+			waiting[d]--
+			if waiting[d] == 0 {
+				if _, done := outcomes[d]; !done {
+					ready = append(ready, d)
+				}
+			}
+		}
+	}
+
+	for _, s := range p.Steps {
+		if _, done := outcomes[s.Name]; !done {
+			outcomes[s.Name] = Skipped
+			if skip != nil {
+				skip(s, "")
+			}
+		}
+	}
+	return outcomes
+}
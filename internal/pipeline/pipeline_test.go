@@ -0,0 +1,186 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ci-status/internal/pipeline"
+)
+
+func TestParse(t *testing.T) {
+	p, err := pipeline.Parse([]byte(`
+steps:
+  - name: build
+    command: go build ./...
+    env:
+      CGO_ENABLED: "0"
+      B: x
+  - name: test
+    context: Tests
+    command: go test ./...
+    timeout: 5m
+    depends_on: [build]
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(p.Steps) != 2 {
+		t.Fatalf("got %d steps, want 2", len(p.Steps))
+	}
+	build, test := p.Steps[0], p.Steps[1]
+	if build.Context != "build" {
+		t.Fatalf("context should default to name, got %q", build.Context)
+	}
+	if got := strings.Join(build.Environ(), " "); got != "B=x CGO_ENABLED=0" {
+		t.Fatalf("Environ() = %q, want sorted KEY=VALUE", got)
+	}
+	if test.Context != "Tests" || test.Timeout != 5*time.Minute {
+		t.Fatalf("test step = %+v", test)
+	}
+	if len(test.DependsOn) != 1 || test.DependsOn[0] != "build" {
+		t.Fatalf("depends_on = %v", test.DependsOn)
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantSub string
+	}{
+		{"empty", `steps: []`, "no steps"},
+		{"missing name", "steps:\n  - command: x\n", "without a name"},
+		{"missing command", "steps:\n  - name: a\n", "no command"},
+		{"duplicate name", "steps:\n  - {name: a, command: x}\n  - {name: a, command: y}\n", "duplicate step"},
+		{"duplicate context", "steps:\n  - {name: a, context: C, command: x}\n  - {name: b, context: C, command: y}\n", "duplicate step context"},
+		{"unknown dep", "steps:\n  - {name: a, command: x, depends_on: [zz]}\n", "unknown step"},
+		{"self dep", "steps:\n  - {name: a, command: x, depends_on: [a]}\n", "itself"},
+		{"cycle", "steps:\n  - {name: a, command: x, depends_on: [b]}\n  - {name: b, command: y, depends_on: [a]}\n", "cycle"},
+		{"bad timeout", "steps:\n  - {name: a, command: x, timeout: soon}\n", "parse pipeline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipeline.Parse([]byte(tt.yaml))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantSub) {
+				t.Fatalf("error %q should contain %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestExecuteOrderAndSkips(t *testing.T) {
+	p, err := pipeline.Parse([]byte(`
+steps:
+  - {name: a, command: ok}
+  - {name: b, command: fail, depends_on: [a]}
+  - {name: c, command: ok, depends_on: [a]}
+  - {name: d, command: ok, depends_on: [b, c]}
+  - {name: e, command: ok, depends_on: [d]}
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var mu sync.Mutex
+	var ran []string
+	skipped := map[string]string{}
+	outcomes := p.Execute(t.Context(), 4,
+		func(_ context.Context, s pipeline.Step) bool {
+			mu.Lock()
+			ran = append(ran, s.Name)
+			mu.Unlock()
+			return s.Command == "ok"
+		},
+		func(s pipeline.Step, failedDep string) {
+			skipped[s.Name] = failedDep
+		},
+	)
+
+	want := map[string]pipeline.Outcome{
+		"a": pipeline.Succeeded,
+		"b": pipeline.Failed,
+		"c": pipeline.Succeeded,
+		"d": pipeline.Skipped,
+		"e": pipeline.Skipped,
+	}
+	for name, o := range want {
+		if outcomes[name] != o {
+			t.Errorf("outcome[%s] = %s, want %s", name, outcomes[name], o)
+		}
+	}
+	if ran[0] != "a" {
+		t.Fatalf("a must run first, got order %v", ran)
+	}
+	if len(ran) != 3 {
+		t.Fatalf("ran %v, want only a, b and c", ran)
+	}
+	if skipped["d"] != "b" || skipped["e"] != "b" {
+		t.Fatalf("skip causes = %v, want d and e skipped because of b", skipped)
+	}
+}
+
+func TestExecuteRespectsConcurrency(t *testing.T) {
+	p, err := pipeline.Parse([]byte(`
+steps:
+  - {name: a, command: x}
+  - {name: b, command: x}
+  - {name: c, command: x}
+  - {name: d, command: x}
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	p.Execute(t.Context(), 2, func(context.Context, pipeline.Step) bool {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return true
+	}, nil)
+	if peak > 2 {
+		t.Fatalf("peak concurrency = %d, want <= 2", peak)
+	}
+}
+
+func TestExecuteCancelledSkipsRemaining(t *testing.T) {
+	p, err := pipeline.Parse([]byte(`
+steps:
+  - {name: a, command: x}
+  - {name: b, command: x, depends_on: [a]}
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	var causes []string
+	outcomes := p.Execute(ctx, 1,
+		func(context.Context, pipeline.Step) bool {
+			cancel()
+			return true
+		},
+		func(s pipeline.Step, failedDep string) {
+			causes = append(causes, s.Name+":"+failedDep)
+		},
+	)
+	if outcomes["b"] != pipeline.Skipped {
+		t.Fatalf("b = %s, want skipped after cancel", outcomes["b"])
+	}
+	if len(causes) != 1 || causes[0] != "b:" {
+		t.Fatalf("skip calls = %v, want b with empty cause", causes)
+	}
+}
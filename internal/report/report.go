@@ -0,0 +1,185 @@
+// Package report describes a finished run (exit code, timing, resource usage)
+// for description templates, --report-json files and summary lines.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"ci-status/internal/executor"
+)
+
+// Report is the outcome of one run. Its fields are what description
+// templates can reference, e.g. "Passed in {{.Duration}} ({{bytes .MaxRSS}})".
+type Report struct {
+	Context string
+	Commit  string
+	// Forge is the forge the status was reported to ("github", "gitea"),
+	// empty when none was detected.
+	Forge   string
+	Command []string
+	State   string
+	// Description is the final status description, after templating.
+	Description string
+	ExitCode    int
+	Attempts    int
+	// StartedAt and FinishedAt bound all attempts.
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Duration is the wall time of all attempts, rounded to milliseconds.
+	Duration time.Duration
+	// UserCPU, SystemCPU, MaxRSS (bytes), InBlocks and OutBlocks come from
+	// the last attempt's rusage; MaxRSS and the block counts are Linux only.
+	UserCPU   time.Duration
+	SystemCPU time.Duration
+	MaxRSS    int64
+	InBlocks  int64
+	OutBlocks int64
+	// PostErrors lists the status posts that failed (the run continues
+	// after them, so they only show up as warnings otherwise).
+	PostErrors []PostError
+}
+
+// PostError is one failed status post.
+type PostError struct {
+	// Status is the post that failed: "pending", "retry", "timeout" or "final".
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// New builds a Report from an executor result.
+// The run is assumed to have just finished.
+func New(context, commit string, command []string, res executor.Result) Report {
+	finished := time.Now()
+	return Report{
+		Context:    context,
+		Commit:     commit,
+		Command:    command,
+		ExitCode:   res.ExitCode,
+		Attempts:   res.Attempts,
+		StartedAt:  finished.Add(-res.Duration),
+		FinishedAt: finished,
+		Duration:   res.Duration.Round(time.Millisecond),
+		UserCPU:    res.Usage.UserCPU.Round(time.Millisecond),
+		SystemCPU:  res.Usage.SystemCPU.Round(time.Millisecond),
+		MaxRSS:     res.Usage.MaxRSS,
+		InBlocks:   res.Usage.InBlocks,
+		OutBlocks:  res.Usage.OutBlocks,
+	}
+}
+
+// funcs are available in description templates.
+var funcs = template.FuncMap{
+	"bytes": FormatBytes,
+}
+
+// Render expands desc as a text/template over r. Descriptions without "{{"
+// are returned unchanged so plain flags never hit template syntax errors.
+func Render(desc string, r Report) (string, error) {
+	if !strings.Contains(desc, "{{") {
+		return desc, nil
+	}
+	tmpl, err := template.New("description").Funcs(funcs).Parse(desc)
+	if err != nil {
+		return desc, fmt.Errorf("parse description template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r); err != nil {
+		return desc, fmt.Errorf("render description template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// Summary is a one-line, /usr/bin/time style digest of r.
+func (r Report) Summary() string {
+	s := fmt.Sprintf("%s: exit %d in %s (user %s, sys %s", r.Context, r.ExitCode, r.Duration, r.UserCPU, r.SystemCPU)
+	if r.MaxRSS > 0 {
+		s += fmt.Sprintf(", max RSS %s, blocks in/out %d/%d", FormatBytes(r.MaxRSS), r.InBlocks, r.OutBlocks)
+	}
+	if r.Attempts > 1 {
+		s += fmt.Sprintf(", %d attempts", r.Attempts)
+	}
+	return s + ")"
+}
+
+// jsonReport is the --report-json layout: durations in seconds and explicit
+// units in field names so other tools need no Go-specific parsing.
+type jsonReport struct {
+	Context          string      `json:"context"`
+	Commit           string      `json:"commit,omitempty"`
+	Forge            string      `json:"forge,omitempty"`
+	Command          []string    `json:"command"`
+	State            string      `json:"state"`
+	Description      string      `json:"description"`
+	ExitCode         int         `json:"exit_code"`
+	Attempts         int         `json:"attempts"`
+	Retries          int         `json:"retries"`
+	StartedAt        time.Time   `json:"started_at"`
+	FinishedAt       time.Time   `json:"finished_at"`
+	DurationSeconds  float64     `json:"duration_seconds"`
+	UserCPUSeconds   float64     `json:"user_cpu_seconds"`
+	SystemCPUSeconds float64     `json:"system_cpu_seconds"`
+	MaxRSSBytes      int64       `json:"max_rss_bytes"`
+	InBlocks         int64       `json:"in_blocks"`
+	OutBlocks        int64       `json:"out_blocks"`
+	PostErrors       []PostError `json:"post_errors"`
+}
+
+// MarshalJSON implements json.Marshaler using the jsonReport layout.
+// post_errors is always an array so consumers can check its length.
+func (r Report) MarshalJSON() ([]byte, error) {
+	postErrors := r.PostErrors
+	if postErrors == nil {
+		postErrors = []PostError{}
+	}
+	return json.Marshal(jsonReport{
+		Context:          r.Context,
+		Commit:           r.Commit,
+		Forge:            r.Forge,
+		Command:          r.Command,
+		State:            r.State,
+		Description:      r.Description,
+		ExitCode:         r.ExitCode,
+		Attempts:         r.Attempts,
+		Retries:          max(r.Attempts-1, 0),
+		StartedAt:        r.StartedAt.UTC().Round(time.Millisecond),
+		FinishedAt:       r.FinishedAt.UTC().Round(time.Millisecond),
+		DurationSeconds:  r.Duration.Seconds(),
+		UserCPUSeconds:   r.UserCPU.Seconds(),
+		SystemCPUSeconds: r.SystemCPU.Seconds(),
+		MaxRSSBytes:      r.MaxRSS,
+		InBlocks:         r.InBlocks,
+		OutBlocks:        r.OutBlocks,
+		PostErrors:       postErrors,
+	})
+}
+
+// WriteJSON writes r to path as indented JSON, replacing any existing file.
+func (r Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// FormatBytes renders n with a binary unit ("412.3 MiB").
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
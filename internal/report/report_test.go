@@ -0,0 +1,103 @@
+package report_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ci-status/internal/executor"
+	"ci-status/internal/report"
+)
+
+func sample() report.Report {
+	return report.New("lint", "abc", []string{"go", "vet"}, executor.Result{
+		ExitCode: 0,
+		Attempts: 2,
+		Duration: 1500*time.Millisecond + 300*time.Microsecond,
+		Usage:    executor.Usage{UserCPU: time.Second, MaxRSS: 3 << 20},
+	})
+}
+
+func TestRender(t *testing.T) {
+	got, err := report.Render("Passed in {{.Duration}} ({{bytes .MaxRSS}})", sample())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "Passed in 1.5s (3.0 MiB)"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if got, err := report.Render("Passed", sample()); err != nil || got != "Passed" {
+		t.Fatalf("plain description changed: %q, %v", got, err)
+	}
+	if got, err := report.Render("Passed {{.Nope}}", sample()); err == nil || got != "Passed {{.Nope}}" {
+		t.Fatalf("unknown field should fail and keep the raw text, got %q, %v", got, err)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	r := sample()
+	r.State = "success"
+	if err := r.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	if got["duration_seconds"] != 1.5 || got["max_rss_bytes"] != float64(3<<20) || got["state"] != "success" || got["attempts"] != float64(2) {
+		t.Fatalf("unexpected report: %s", data)
+	}
+	if got["retries"] != float64(1) {
+		t.Fatalf("retries = %v, want 1", got["retries"])
+	}
+	if errs, ok := got["post_errors"].([]any); !ok || len(errs) != 0 {
+		t.Fatalf("post_errors = %v, want empty array", got["post_errors"])
+	}
+}
+
+func TestJSONIncludesForgeDescriptionAndPostErrors(t *testing.T) {
+	r := sample()
+	r.Forge = "gitea"
+	r.Description = "Passed in 1.5s"
+	r.PostErrors = []report.PostError{{Status: "pending", Error: "github api error: 502 Bad Gateway"}}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Forge       string             `json:"forge"`
+		Description string             `json:"description"`
+		StartedAt   string             `json:"started_at"`
+		PostErrors  []report.PostError `json:"post_errors"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Forge != "gitea" || got.Description != "Passed in 1.5s" || got.StartedAt == "" {
+		t.Fatalf("unexpected report: %s", data)
+	}
+	if len(got.PostErrors) != 1 || got.PostErrors[0].Status != "pending" {
+		t.Fatalf("post_errors = %+v", got.PostErrors)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		512:                      "512 B",
+		1536:                     "1.5 KiB",
+		412*1024*1024 + 300*1024: "412.3 MiB",
+		3 << 30:                  "3.0 GiB",
+	} {
+		if got := report.FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}